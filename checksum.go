@@ -0,0 +1,75 @@
+package fs
+
+import (
+	"hash"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Checksum walks the tree rooted at root, as Walk does, and returns
+// the digest of the content of each regular file in it, computed
+// with a hash from h and keyed by the file's path relative to root,
+// as reported by RelPath. Files are read by up to GOMAXPROCS
+// goroutines at once. If any entry has an error, or any file cannot
+// be read, Checksum stops and returns the first error it sees.
+func Checksum(root string, h func() hash.Hash) (map[string][]byte, error) {
+	w := Walk(root)
+	type file struct{ rel, path string }
+	files := make(chan file)
+	var (
+		mu    sync.Mutex
+		sums  = make(map[string][]byte)
+		first error
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range files {
+				sum, err := checksum(w.fs, f.path, h())
+				mu.Lock()
+				if err != nil && first == nil {
+					first = err
+				}
+				sums[f.rel] = sum
+				mu.Unlock()
+			}
+		}()
+	}
+	for w.Step() {
+		mu.Lock()
+		if first == nil {
+			first = w.Err()
+		}
+		failed := first != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		if w.Stat().Mode().IsRegular() {
+			files <- file{w.RelPath(), w.Path()}
+		}
+	}
+	close(files)
+	wg.Wait()
+	if first != nil {
+		return nil, first
+	}
+	return sums, nil
+}
+
+// checksum returns the digest computed by h of the content of the
+// named file on fsys.
+func checksum(fsys FileSystem, name string, h hash.Hash) ([]byte, error) {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
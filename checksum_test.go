@@ -0,0 +1,48 @@
+package fs_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestChecksum(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a":     "hello",
+		"b/c":   "",
+		"b/d/e": "world",
+	}
+	want := make(map[string][]byte)
+	for name, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(data))
+		want[filepath.FromSlash(name)] = sum[:]
+	}
+	if err := os.Symlink("a", filepath.Join(root, "link")); err != nil {
+		t.Logf("symlinks not supported: %v", err)
+	}
+	got, err := fs.Checksum(root, sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Checksum = %x, want %x", got, want)
+	}
+
+	if _, err := fs.Checksum(filepath.Join(root, "missing"), sha256.New); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Checksum of missing root: %v, want not exist", err)
+	}
+}
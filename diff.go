@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"os"
+	"strings"
+)
+
+// SameFile reports whether a and b describe the same file,
+// as os.SameFile does. Only FileInfos returned by the os package,
+// as by the FileSystem that Walk uses, can describe the same file.
+func SameFile(a, b os.FileInfo) bool {
+	return os.SameFile(a, b)
+}
+
+// A DiffKind says how an entry differs between two trees.
+type DiffKind int
+
+const (
+	Added    DiffKind = iota // only in the second tree
+	Removed                  // only in the first tree
+	Modified                 // in both trees, but different
+)
+
+// A DiffEntry is an entry that differs between two trees,
+// as reported by Diff.
+type DiffEntry struct {
+	Path string      // relative to the roots, as reported by RelPath
+	Kind DiffKind    // how the entry differs
+	A, B os.FileInfo // the entry in each tree, or nil if it is not there
+}
+
+// Diff walks the trees rooted at rootA and rootB together, as Walk
+// does, and returns the entries that differ between them, in the
+// order visited. An entry differs if it is in only one of the trees,
+// if its type differs, or if it is not a directory and its size or
+// modification time differs; the content of files is not compared.
+// Directories in only one tree, or that are directories in only one
+// tree, are reported, but not their entries. If any entry has an
+// error, Diff stops and returns it.
+func Diff(rootA, rootB string) ([]DiffEntry, error) {
+	a, b := Walk(rootA), Walk(rootB)
+	okA, okB := a.Step(), b.Step()
+	var diffs []DiffEntry
+	for okA || okB {
+		if err := a.Err(); okA && err != nil {
+			return nil, err
+		}
+		if err := b.Err(); okB && err != nil {
+			return nil, err
+		}
+		c := 0
+		switch {
+		case !okB:
+			c = -1
+		case !okA:
+			c = 1
+		default:
+			c = compareElems(a.cur.elems(), b.cur.elems())
+		}
+		switch {
+		case c < 0:
+			diffs = append(diffs, DiffEntry{a.RelPath(), Removed, a.Stat(), nil})
+			a.SkipDir()
+			okA = a.Step()
+		case c > 0:
+			diffs = append(diffs, DiffEntry{b.RelPath(), Added, nil, b.Stat()})
+			b.SkipDir()
+			okB = b.Step()
+		default:
+			ia, ib := a.Stat(), b.Stat()
+			if ia.Mode().Type() != ib.Mode().Type() {
+				a.SkipDir()
+				b.SkipDir()
+			}
+			if modified(ia, ib) {
+				diffs = append(diffs, DiffEntry{a.RelPath(), Modified, ia, ib})
+			}
+			okA, okB = a.Step(), b.Step()
+		}
+	}
+	return diffs, nil
+}
+
+// compareElems compares two paths, given as their elements, in the
+// order in which Walk visits them.
+func compareElems(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// modified reports whether a and b, which have the same path
+// in two trees, differ as Diff describes.
+func modified(a, b os.FileInfo) bool {
+	if a.Mode().Type() != b.Mode().Type() {
+		return true
+	}
+	return !a.IsDir() && (a.Size() != b.Size() || !a.ModTime().Equal(b.ModTime()))
+}
@@ -0,0 +1,100 @@
+package fs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kr/fs"
+)
+
+func TestDiff(t *testing.T) {
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(root string, files map[string]string) {
+		for name, data := range files {
+			p := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(p, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	a, b := t.TempDir(), t.TempDir()
+	write(a, map[string]string{
+		"a":     "same",
+		"a-b":   "gone",
+		"c/d":   "old",
+		"c/e":   "same",
+		"f/g":   "dir",
+		"h/i/j": "gone",
+	})
+	write(b, map[string]string{
+		"a/1": "",
+		"c/d": "new!",
+		"c/e": "same",
+		"f":   "file",
+		"k/l": "new",
+	})
+	var got []string
+	diffs, err := fs.Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		s := filepath.ToSlash(d.Path)
+		switch d.Kind {
+		case fs.Added:
+			s += " added"
+		case fs.Removed:
+			s += " removed"
+		case fs.Modified:
+			s += " modified"
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"a modified",
+		"a-b removed",
+		"c/d modified",
+		"f modified",
+		"h removed",
+		"k added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+	if d := diffs[len(diffs)-1]; d.A != nil || d.B == nil || !d.B.IsDir() {
+		t.Errorf("added entry has A = %v, B = %v", d.A, d.B)
+	}
+
+	if _, err := fs.Diff(a, filepath.Join(b, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Diff with missing root: %v, want not exist", err)
+	}
+}
+
+func TestSameFile(t *testing.T) {
+	root := t.TempDir()
+	p := filepath.Join(root, "a")
+	if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	walker := fs.Walk(root)
+	for walker.Step() {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fs.SameFile(walker.Stat(), info), walker.Path() == p; got != want {
+			t.Errorf("SameFile(%s, %s) = %t, want %t", walker.Path(), p, got, want)
+		}
+	}
+}
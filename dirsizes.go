@@ -0,0 +1,33 @@
+package fs
+
+// DirSizes walks the tree rooted at root, as WalkPostOrder does,
+// and returns for each directory the total size of the regular
+// files beneath it, at any depth, keyed by the directory's path.
+// Symbolic links are not followed, and count for nothing. If any
+// entry has an error, DirSizes stops and returns it.
+func DirSizes(root string) (map[string]int64, error) {
+	w := WalkPostOrder(root)
+	sizes := make(map[string]int64)
+	var acc []int64 // acc[d] is the total so far of the directory at depth d
+	for w.Step() {
+		if err := w.Err(); err != nil {
+			return nil, err
+		}
+		d := w.Depth()
+		for len(acc) <= d {
+			acc = append(acc, 0)
+		}
+		var n int64
+		switch {
+		case w.IsDir():
+			n, acc[d] = acc[d], 0
+			sizes[w.Path()] = n
+		case w.Stat().Mode().IsRegular():
+			n = w.Size()
+		}
+		if d > 0 {
+			acc[d-1] += n
+		}
+	}
+	return sizes, nil
+}
@@ -0,0 +1,56 @@
+package fs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestDirSizes(t *testing.T) {
+	root := t.TempDir()
+	for name, size := range map[string]int{
+		"a":     1,
+		"b/c":   10,
+		"b/d/e": 100,
+		"b/d/f": 1000,
+		"g/h":   10000,
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("b", filepath.Join(root, "link")); err != nil {
+		t.Logf("symlinks not supported: %v", err)
+	}
+	got, err := fs.DirSizes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{
+		root:                          11111,
+		filepath.Join(root, "b"):      1110,
+		filepath.Join(root, "b", "d"): 1100,
+		filepath.Join(root, "empty"):  0,
+		filepath.Join(root, "g"):      10000,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DirSizes = %v, want %v", got, want)
+	}
+
+	if _, err := fs.DirSizes(filepath.Join(root, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("DirSizes of missing root: %v, want not exist", err)
+	}
+}
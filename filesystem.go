@@ -1,36 +1,328 @@
 package fs
 
 import (
+	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 // FileSystem defines the methods of an abstract filesystem.
 type FileSystem interface {
 
 	// ReadDir reads the directory named by dirname and returns a
-	// list of directory entries.
+	// list of directory entries sorted by filename.
 	ReadDir(dirname string) ([]os.FileInfo, error)
 
+	// ReadDirUnsorted is like ReadDir, but returns the entries in
+	// no particular order, which can be faster. Implementations
+	// that cannot do better may return them sorted.
+	ReadDirUnsorted(dirname string) ([]os.FileInfo, error)
+
 	// Lstat returns a FileInfo describing the named file. If the file is a
 	// symbolic link, the returned FileInfo describes the symbolic link. Lstat
 	// makes no attempt to follow the link.
 	Lstat(name string) (os.FileInfo, error)
 
+	// Stat returns a FileInfo describing the named file. If the file is a
+	// symbolic link, the returned FileInfo describes the file it links to.
+	Stat(name string) (os.FileInfo, error)
+
+	// Readlink returns the destination of the named symbolic link.
+	Readlink(name string) (string, error)
+
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+
+	// MkdirAll creates the named directory, along with any parents
+	// that do not exist, with permission bits perm. If the directory
+	// already exists, MkdirAll does nothing and returns nil.
+	MkdirAll(path string, perm os.FileMode) error
+
+	// WriteFile writes data to the named file, creating it with
+	// permission bits perm if necessary, and truncating it otherwise.
+	WriteFile(name string, data []byte, perm os.FileMode) error
+
+	// Remove removes the named file or empty directory.
+	Remove(name string) error
+
+	// RemoveAll removes path and everything it contains. If the
+	// path does not exist, RemoveAll does nothing and returns nil.
+	RemoveAll(path string) error
+
 	// Join joins any number of path elements into a single path, adding a
 	// separator if necessary. The result is Cleaned; in particular, all
 	// empty strings are ignored.
 	//
 	// The separator is FileSystem specific.
 	Join(elem ...string) string
+
+	// PathSeparator returns the separator that Join places
+	// between path elements.
+	PathSeparator() byte
+
+	// Split splits path immediately following the final separator,
+	// separating it into a directory and file name component. If
+	// there is no separator in path, Split returns an empty dir and
+	// file set to path. The returned values have the property that
+	// path = dir+file.
+	Split(path string) (dir, file string)
+
+	// Base returns the last element of path. Trailing separators are
+	// removed before extracting the last element. If the path is empty,
+	// Base returns ".". If the path consists entirely of separators,
+	// Base returns a single separator.
+	Base(path string) string
+
+	// Rel returns a relative path that is lexically equivalent to
+	// targpath when joined to basepath with Join. An error is returned
+	// if targpath can't be made relative to basepath.
+	Rel(basepath, targpath string) (string, error)
+
+	// Abs returns an absolute representation of path. If the path
+	// is not absolute, it is joined with the FileSystem's notion of
+	// the current directory. The result is Cleaned.
+	Abs(path string) (string, error)
+
+	// Glob returns the names of all files matching pattern, or nil
+	// if there is no matching file. The syntax of patterns is that
+	// of filepath.Match, using the FileSystem's separator, and the
+	// pattern may describe hierarchical names. Glob ignores I/O
+	// errors; the only possible error is a malformed pattern.
+	Glob(pattern string) ([]string, error)
+}
+
+// A BatchFileSystem is a FileSystem that can also read a directory
+// a few entries at a time, so that a huge directory need not be
+// held in memory all at once. See Walker.SetReadDirBatch.
+type BatchFileSystem interface {
+	FileSystem
+
+	// ReadDirBatch opens the directory named by dirname to be
+	// read at most n entries at a time, in no particular order.
+	ReadDirBatch(dirname string, n int) (DirIterator, error)
+}
+
+// A DirIterator reads the entries of a directory opened by
+// ReadDirBatch.
+type DirIterator interface {
+	// Next returns the next entries of the directory. If it
+	// returns no entries, it also returns a non-nil error,
+	// which is io.EOF at the end of the directory.
+	Next() ([]os.FileInfo, error)
+
+	// Close closes the directory.
+	Close() error
 }
 
 // fs represents a FileSystem provided by the os package.
 type fs struct{}
 
+// OS returns the FileSystem provided by the os package,
+// as used by Walk. Wrappers can embed it to delegate the
+// methods they do not override.
+func OS() FileSystem {
+	return new(fs)
+}
+
 func (f *fs) ReadDir(dirname string) ([]os.FileInfo, error) { return ioutil.ReadDir(dirname) }
 
+func (f *fs) ReadDirUnsorted(dirname string) ([]os.FileInfo, error) {
+	d, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	list, err := d.Readdir(-1)
+	d.Close()
+	return list, err
+}
+
+func (f *fs) ReadDirBatch(dirname string, n int) (DirIterator, error) {
+	d, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	return &osDirIterator{d, n}, nil
+}
+
+// osDirIterator reads an open directory with Readdir.
+type osDirIterator struct {
+	d *os.File
+	n int
+}
+
+func (it *osDirIterator) Next() ([]os.FileInfo, error) { return it.d.Readdir(it.n) }
+
+func (it *osDirIterator) Close() error { return it.d.Close() }
+
 func (f *fs) Lstat(name string) (os.FileInfo, error) { return os.Lstat(name) }
 
+func (f *fs) Readlink(name string) (string, error) { return os.Readlink(name) }
+
+func (f *fs) Open(name string) (io.ReadCloser, error) { return os.Open(name) }
+
+func (f *fs) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+func (f *fs) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+func (f *fs) Remove(name string) error { return os.Remove(name) }
+
+func (f *fs) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+func (f *fs) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
 func (f *fs) Join(elem ...string) string { return filepath.Join(elem...) }
+
+func (f *fs) PathSeparator() byte { return os.PathSeparator }
+
+func (f *fs) Split(p string) (dir, file string) { return filepath.Split(p) }
+
+func (f *fs) Base(p string) string { return filepath.Base(p) }
+
+func (f *fs) Rel(basepath, targpath string) (string, error) { return filepath.Rel(basepath, targpath) }
+
+func (f *fs) Abs(p string) (string, error) { return filepath.Abs(p) }
+
+func (f *fs) Glob(pattern string) ([]string, error) { return filepath.Glob(pattern) }
+
+// resolveLinks returns name, cleaned, with the symbolic links in it
+// resolved through fsys; the final element is resolved only if
+// follow is true. If an element cannot be resolved, resolveLinks
+// returns the error, along with name resolved as far as it could be
+// and the rest of it joined on unchanged.
+func resolveLinks(fsys FileSystem, name string, follow bool) (string, error) {
+	sep := string(fsys.PathSeparator())
+	rest := strings.Split(name, sep)
+	dir := ""
+	if strings.HasPrefix(name, sep) {
+		dir = sep
+	}
+	for links := 0; len(rest) > 0; {
+		e := rest[0]
+		rest = rest[1:]
+		if e == "" || e == "." {
+			continue
+		}
+		p := fsys.Join(dir, e)
+		info, err := fsys.Lstat(p)
+		if err != nil {
+			return fsys.Join(append([]string{p}, rest...)...), err
+		}
+		if info.Mode()&os.ModeSymlink == 0 || len(rest) == 0 && !follow {
+			dir = p
+			continue
+		}
+		if links++; links > maxLinks {
+			err := &os.PathError{Op: "readlink", Path: p, Err: os.ErrInvalid}
+			return fsys.Join(append([]string{p}, rest...)...), err
+		}
+		dst, err := fsys.Readlink(p)
+		if err != nil {
+			return fsys.Join(append([]string{p}, rest...)...), err
+		}
+		if strings.HasPrefix(dst, sep) {
+			dir = sep
+		}
+		rest = append(strings.Split(dst, sep), rest...)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return dir, nil
+}
+
+// slashRel is filepath.Rel for slash-separated paths.
+func slashRel(basepath, targpath string) (string, error) {
+	base, targ := path.Clean(basepath), path.Clean(targpath)
+	if base == targ {
+		return ".", nil
+	}
+	if path.IsAbs(base) != path.IsAbs(targ) {
+		return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
+	}
+	b, t := slashElems(base), slashElems(targ)
+	i := 0
+	for i < len(b) && i < len(t) && b[i] == t[i] {
+		i++
+	}
+	var rel []string
+	for _, e := range b[i:] {
+		if e == ".." {
+			return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
+		}
+		rel = append(rel, "..")
+	}
+	return path.Join(append(rel, t[i:]...)...), nil
+}
+
+// slashElems returns the elements of the clean slash-separated path p.
+func slashElems(p string) []string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// slashGlob is filepath.Glob for a FileSystem with slash-separated
+// paths, built on its Lstat, Stat, and ReadDir methods.
+func slashGlob(fsys FileSystem, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, err := fsys.Lstat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+	dir, file := path.Split(pattern)
+	switch dir {
+	case "":
+		dir = "."
+	case "/":
+	default:
+		dir = dir[:len(dir)-1] // chop off trailing slash
+	}
+	if !hasMeta(dir) {
+		return slashGlobDir(fsys, dir, file, nil), nil
+	}
+	dirs, err := slashGlob(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, d := range dirs {
+		matches = slashGlobDir(fsys, d, file, matches)
+	}
+	return matches, nil
+}
+
+// slashGlobDir appends to matches the paths of the entries of dir
+// whose names match pattern, which must be well formed.
+func slashGlobDir(fsys FileSystem, dir, pattern string, matches []string) []string {
+	if info, err := fsys.Stat(dir); err != nil || !info.IsDir() {
+		return matches
+	}
+	list, err := fsys.ReadDir(dir)
+	if err != nil {
+		return matches
+	}
+	for _, info := range list {
+		if ok, _ := path.Match(pattern, info.Name()); ok {
+			matches = append(matches, path.Join(dir, info.Name()))
+		}
+	}
+	return matches
+}
+
+// hasMeta reports whether p contains any of the special characters
+// recognized by path.Match.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
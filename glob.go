@@ -0,0 +1,65 @@
+package fs
+
+import (
+	"path"
+	"strings"
+)
+
+// WalkGlob returns a new Walker rooted at root that visits only
+// the entries whose path relative to root matches at least one of
+// patterns. Directories that cannot contain a match are not read.
+//
+// Patterns use the syntax of path.Match, with elements separated by
+// slashes whatever the FileSystem's separator; an element "**"
+// matches any number of path elements, including none. For example,
+// "**/*.go" matches every file ending in ".go". Malformed patterns
+// match nothing.
+func WalkGlob(root string, patterns ...string) *Walker {
+	return Walk(root, WithGlob(patterns...))
+}
+
+// WithGlob makes the Walker visit only the entries matching at
+// least one of patterns, as WalkGlob does.
+func WithGlob(patterns ...string) Option {
+	var pats [][]string
+	for _, p := range patterns {
+		pats = append(pats, strings.Split(p, "/"))
+	}
+	return func(w *Walker) {
+		w.rules = append(w.rules, func(_ *Walker, it *item) (visit, descend bool) {
+			elems := it.elems()
+			for _, p := range pats {
+				visit = visit || globMatch(p, elems, false)
+				descend = descend || globMatch(p, elems, true)
+			}
+			return visit, descend
+		})
+	}
+}
+
+// globMatch reports whether the path elements elems match the
+// pattern elements pat. If prefix is true, it instead reports
+// whether a path below elems could match pat.
+func globMatch(pat, elems []string, prefix bool) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			if prefix {
+				return true
+			}
+			for i := 0; i <= len(elems); i++ {
+				if globMatch(pat[1:], elems[i:], false) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return prefix
+		}
+		if ok, _ := path.Match(pat[0], elems[0]); !ok {
+			return false
+		}
+		pat, elems = pat[1:], elems[1:]
+	}
+	return len(elems) == 0 && !prefix
+}
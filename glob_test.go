@@ -0,0 +1,56 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestWalkGlob(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"**"}, []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}},
+		{[]string{"*"}, []string{"a", "b", "c", "d"}},
+		{[]string{"**/u"}, []string{"d/z/u"}},
+		{[]string{"d/**"}, []string{"d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}},
+		{[]string{"d/*/v", "[ab]"}, []string{"a", "b", "d/z/v"}},
+		{[]string{"d/[z"}, nil},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		walker := fs.WalkGlob(tree.name, test.patterns...)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(walker.RelPath()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("WalkGlob(%q) = %q, want %q", test.patterns, got, test.want)
+		}
+	}
+}
+
+func TestWalkGlobExpand(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var got []string
+	walker := fs.WalkGlob(tree.name, "d/**/u", "a").Expand(filepath.Join(tree.name, "d"))
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	if want := []string{"d/z/u"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expanded walk = %q, want %q", got, want)
+	}
+}
@@ -1 +1,3 @@
-module "github.com/kr/fs"
+module github.com/kr/fs
+
+go 1.25
@@ -0,0 +1,146 @@
+package fs
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// An ignorePattern is one line of a gitignore file.
+type ignorePattern struct {
+	elems   []string // slash-separated elements, as for globMatch
+	negate  bool     // the pattern began with "!"
+	dirOnly bool     // the pattern ended with "/"
+}
+
+// parseIgnore parses gitignore patterns, skipping blank lines and
+// comments.
+func parseIgnore(lines []string) []ignorePattern {
+	var pats []ignorePattern
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var p ignorePattern
+		if line[0] == '!' {
+			p.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A pattern with a slash before its end is relative to the
+		// directory it comes from; otherwise it matches at any depth.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		p.elems = strings.Split(line, "/")
+		pats = append(pats, p)
+	}
+	return pats
+}
+
+// ignored reports whether pats match the entry with path elements
+// elems, relative to the patterns' directory, and if so, whether
+// the last pattern to match ignores it rather than negating that.
+func ignored(pats []ignorePattern, elems []string, dir bool) (ignore, matched bool) {
+	for _, p := range pats {
+		if p.dirOnly && !dir {
+			continue
+		}
+		if globMatch(p.elems, elems, false) {
+			ignore, matched = !p.negate, true
+		}
+	}
+	return ignore, matched
+}
+
+// SetIgnore sets patterns, in the syntax of gitignore, to pass over
+// entries whose path relative to the root matches. An ignored
+// directory is not descended into. As in gitignore, a pattern
+// beginning with "!" re-includes what an earlier pattern ignored, a
+// pattern ending with "/" matches only directories, and a pattern
+// containing any other "/" is matched against the whole relative
+// path rather than against any trailing part of it. Elements of a
+// pattern are always separated by "/", whatever the FileSystem's
+// separator. The root is never ignored.
+// SetIgnore must be called before the first call to Step.
+func (w *Walker) SetIgnore(patterns []string) {
+	pats := parseIgnore(patterns)
+	w.rules = append(w.rules, func(_ *Walker, it *item) (visit, descend bool) {
+		if it.parent == nil {
+			return true, true
+		}
+		ignore, _ := ignored(pats, it.elems(), it.info.IsDir())
+		return !ignore, !ignore
+	})
+}
+
+// WithIgnore sets patterns, in the syntax of gitignore, to pass over
+// the entries they match, as SetIgnore does.
+func WithIgnore(patterns []string) Option {
+	return func(w *Walker) { w.SetIgnore(patterns) }
+}
+
+// WalkGitIgnore returns a new Walker rooted at root that honors the
+// .gitignore files it finds, as git does: the patterns in a
+// directory's .gitignore, in the syntax described for SetIgnore,
+// apply to the entries beneath that directory, relative to it, and
+// those of a deeper .gitignore take precedence over those of the
+// directories above it. Ignored entries are passed over, and ignored
+// directories are not descended into. The root is never ignored.
+// Each .gitignore is read once in a walk; after Reset, they are
+// read again.
+func WalkGitIgnore(root string) *Walker {
+	return Walk(root, WithGitIgnore())
+}
+
+// WithGitIgnore makes the Walker honor the .gitignore files it
+// finds, as WalkGitIgnore does.
+func WithGitIgnore() Option {
+	return func(w *Walker) {
+		w.rules = append(w.rules, func(w *Walker, it *item) (visit, descend bool) {
+			if it.parent == nil {
+				return true, true
+			}
+			if w.ignores == nil {
+				w.ignores = make(map[string][]ignorePattern)
+			}
+			elems := it.elems()
+			for d := it.parent; d != nil; d = d.parent {
+				pats, ok := w.ignores[d.path]
+				if !ok {
+					pats = w.readIgnore(d.path)
+					w.ignores[d.path] = pats
+				}
+				if ignore, matched := ignored(pats, elems[d.depth:], it.info.IsDir()); matched {
+					return !ignore, !ignore
+				}
+			}
+			return true, true
+		})
+	}
+}
+
+// readIgnore returns the patterns in the .gitignore file in dir,
+// if there is one.
+func (w *Walker) readIgnore(dir string) []ignorePattern {
+	r, err := w.fs.Open(w.fs.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	return parseIgnore(strings.Split(string(b), "\n"))
+}
@@ -0,0 +1,128 @@
+package fs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestWalkIgnore(t *testing.T) {
+	m := fs.MapFS{
+		"r/a.go":           mapFile,
+		"r/a.o":            mapFile,
+		"r/keep.o":         mapFile,
+		"r/build/x":        mapFile,
+		"r/src/build":      mapFile,
+		"r/src/doc/y":      mapFile,
+		"r/doc/z":          mapFile,
+		"r/node_modules/q": mapFile,
+		"r/src/vendor/v":   mapFile,
+		"r/#notcomment":    mapFile,
+	}
+	walker := fs.WalkFS("r", m)
+	walker.SetIgnore([]string{
+		"# comment",
+		"",
+		"*.o",
+		"!keep.o",
+		"build/",
+		"/doc",
+		"**/node_modules",
+		"src/vendor/",
+		`\#notcomment`,
+	})
+	var got []string
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	want := []string{
+		"r",
+		"r/a.go",
+		"r/keep.o",
+		"r/src",
+		"r/src/build",
+		"r/src/doc",
+		"r/src/doc/y",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkGitIgnore(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":       "*.o\n/build\n",
+		"a.go":             "",
+		"a.o":              "",
+		"build/x":          "",
+		"other/local":      "",
+		"sub/.gitignore":   "# keep this one\n!keep.o\nlocal/\n",
+		"sub/b.o":          "",
+		"sub/build":        "",
+		"sub/keep.o":       "",
+		"sub/local/y":      "",
+		"sub/deep/local/z": "",
+	}
+	for name, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	walker := fs.WalkGitIgnore(root)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	want := []string{
+		".",
+		".gitignore",
+		"a.go",
+		"other",
+		"other/local",
+		"sub",
+		"sub/.gitignore",
+		"sub/build",
+		"sub/deep",
+		"sub/keep.o",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	got = nil
+	sub := walker.Expand(filepath.Join(root, "sub"))
+	for sub.Step() {
+		if err := sub.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(sub.RelPath()))
+	}
+	want = want[5:]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expanded walk = %q, want %q", got, want)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, ".gitignore"), []byte("/build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	walker.Reset(root)
+	for walker.Step() {
+		found = found || walker.RelPath() == "a.o"
+	}
+	if !found {
+		t.Errorf("a.o still ignored after changing .gitignore and calling Reset")
+	}
+}
@@ -0,0 +1,222 @@
+package fs
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ioFS is a FileSystem backed by an io/fs.FS.
+type ioFS struct {
+	fsys iofs.FS
+}
+
+// FromIOFS returns a FileSystem backed by fsys, such as an
+// embed.FS or a *zip.Reader.
+//
+// Paths on the returned FileSystem follow the rules of io/fs:
+// they are slash-separated on every platform, unrooted, and the
+// root is named ".", which is also the current directory, so Abs
+// only cleans its argument. Join uses path.Join, so a walk over
+// the result reports slash-separated paths even on systems whose
+// own separator is different; use filepath.FromSlash to convert
+// them.
+//
+// Lstat and Readlink use io/fs.Lstat and io/fs.ReadLink, so
+// they see symbolic links only if fsys is an io/fs.ReadLinkFS;
+// otherwise Lstat is the same as Stat, and Readlink always fails.
+// Package io/fs cannot modify files, so MkdirAll, WriteFile,
+// Remove, and RemoveAll return ErrReadOnly, wrapped in an
+// *os.PathError.
+func FromIOFS(fsys iofs.FS) FileSystem {
+	return ioFS{fsys}
+}
+
+func (f ioFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	entries, err := iofs.ReadDir(f.fsys, dirname)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]os.FileInfo, len(entries))
+	for i, e := range entries {
+		if list[i], err = e.Info(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (f ioFS) ReadDirUnsorted(dirname string) ([]os.FileInfo, error) { return f.ReadDir(dirname) }
+
+func (f ioFS) Lstat(name string) (os.FileInfo, error) { return iofs.Lstat(f.fsys, name) }
+
+func (f ioFS) Stat(name string) (os.FileInfo, error) { return iofs.Stat(f.fsys, name) }
+
+func (f ioFS) Readlink(name string) (string, error) { return iofs.ReadLink(f.fsys, name) }
+
+func (f ioFS) Open(name string) (io.ReadCloser, error) { return f.fsys.Open(name) }
+
+func (f ioFS) MkdirAll(path string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: path, Err: ErrReadOnly}
+}
+
+func (f ioFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+}
+
+func (f ioFS) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+func (f ioFS) RemoveAll(path string) error {
+	return &os.PathError{Op: "removeall", Path: path, Err: ErrReadOnly}
+}
+
+func (f ioFS) Join(elem ...string) string { return path.Join(elem...) }
+
+func (f ioFS) PathSeparator() byte { return '/' }
+
+func (f ioFS) Split(p string) (dir, file string) { return path.Split(p) }
+
+func (f ioFS) Base(p string) string { return path.Base(p) }
+
+func (f ioFS) Rel(basepath, targpath string) (string, error) {
+	return slashRel(basepath, targpath)
+}
+
+func (f ioFS) Abs(p string) (string, error) { return path.Clean(p), nil }
+
+func (f ioFS) Glob(pattern string) ([]string, error) { return iofs.Glob(f.fsys, pattern) }
+
+// toIOFS is an io/fs.FS backed by a FileSystem.
+type toIOFS struct {
+	fsys FileSystem
+}
+
+// ToIOFS returns an io/fs.FS backed by fsys, for use with
+// functions such as fs.WalkDir and fs.Glob in package io/fs.
+// The result also implements io/fs.ReadDirFS and io/fs.StatFS.
+//
+// Names given to the result follow the rules of io/fs, and are
+// converted to paths on fsys by splitting them at slashes and
+// joining the elements with fsys.Join. Symbolic links are
+// followed, as io/fs requires.
+func ToIOFS(fsys FileSystem) iofs.FS {
+	return toIOFS{fsys}
+}
+
+// path converts the io/fs name to a path on f.fsys.
+func (f toIOFS) path(op, name string) (string, error) {
+	if !iofs.ValidPath(name) {
+		return "", &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+	return f.fsys.Join(strings.Split(name, "/")...), nil
+}
+
+func (f toIOFS) Open(name string) (iofs.File, error) {
+	p, err := f.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.fsys.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &ioDir{fsys: f.fsys, path: p, info: info}, nil
+	}
+	r, err := f.fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	return &ioFile{r, info}, nil
+}
+
+func (f toIOFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	p, err := f.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	list, err := f.fsys.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	return dirEntries(list), nil
+}
+
+func (f toIOFS) Stat(name string) (iofs.FileInfo, error) {
+	p, err := f.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.fsys.Stat(p)
+}
+
+// ioFile is a regular file opened through toIOFS.
+type ioFile struct {
+	io.ReadCloser
+	info os.FileInfo
+}
+
+func (f *ioFile) Stat() (iofs.FileInfo, error) { return f.info, nil }
+
+// ioDir is a directory opened through toIOFS.
+type ioDir struct {
+	fsys FileSystem
+	path string
+	info os.FileInfo
+	list []os.FileInfo
+	read bool // whether list has been read
+}
+
+func (d *ioDir) Stat() (iofs.FileInfo, error) { return d.info, nil }
+
+func (d *ioDir) Read([]byte) (int, error) {
+	return 0, &iofs.PathError{Op: "read", Path: d.path, Err: iofs.ErrInvalid}
+}
+
+func (d *ioDir) Close() error { return nil }
+
+func (d *ioDir) ReadDir(n int) ([]iofs.DirEntry, error) {
+	if !d.read {
+		list, err := d.fsys.ReadDir(d.path)
+		if err != nil {
+			return nil, err
+		}
+		d.list, d.read = list, true
+	}
+	if n <= 0 {
+		list := d.list
+		d.list = nil
+		return dirEntries(list), nil
+	}
+	if len(d.list) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.list) {
+		n = len(d.list)
+	}
+	list := d.list[:n]
+	d.list = d.list[n:]
+	return dirEntries(list), nil
+}
+
+func dirEntries(list []os.FileInfo) []iofs.DirEntry {
+	entries := make([]iofs.DirEntry, len(list))
+	for i, info := range list {
+		entries[i] = dirEntry{info}
+	}
+	return entries
+}
+
+// dirEntry is an io/fs.DirEntry for an os.FileInfo.
+type dirEntry struct {
+	info os.FileInfo
+}
+
+func (e dirEntry) Name() string                 { return e.info.Name() }
+func (e dirEntry) IsDir() bool                  { return e.info.IsDir() }
+func (e dirEntry) Type() iofs.FileMode          { return e.info.Mode().Type() }
+func (e dirEntry) Info() (iofs.FileInfo, error) { return e.info, nil }
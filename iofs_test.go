@@ -0,0 +1,95 @@
+package fs_test
+
+import (
+	"errors"
+	iofs "io/fs"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kr/fs"
+)
+
+func TestFromIOFS(t *testing.T) {
+	fsys := fs.FromIOFS(fstest.MapFS{
+		"a":     {Data: []byte("hello")},
+		"b/c":   {},
+		"b/d/e": {},
+	})
+	var got []string
+	walker := fs.WalkFS(".", fsys)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, walker.Path())
+	}
+	want := []string{".", "a", "b", "b/c", "b/d", "b/d/e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	r, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if b, _ := ioutil.ReadAll(r); string(b) != "hello" {
+		t.Errorf("read %q, want %q", b, "hello")
+	}
+	if got, err := fsys.Glob("b/*"); err != nil || !reflect.DeepEqual(got, []string{"b/c", "b/d"}) {
+		t.Errorf("Glob = %q, %v, want %q", got, err, []string{"b/c", "b/d"})
+	}
+	if err := fsys.WriteFile("a", nil, 0644); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("WriteFile: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if err := fsys.MkdirAll("b/f", 0755); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("MkdirAll: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if err := fsys.Remove("a"); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("Remove: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if err := fsys.RemoveAll("b"); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("RemoveAll: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if _, err := fsys.Readlink("a"); err == nil {
+		t.Errorf("Readlink of a regular file: no error")
+	}
+}
+
+func TestFromIOFSLinks(t *testing.T) {
+	fsys := fs.FromIOFS(fstest.MapFS{
+		"a":    {Data: []byte("hello")},
+		"link": {Data: []byte("a"), Mode: iofs.ModeSymlink},
+	})
+	if info, err := fsys.Lstat("link"); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat(link) = %v, %v, want a symbolic link", info, err)
+	}
+	if info, err := fsys.Stat("link"); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("Stat(link) = %v, %v, want a regular file", info, err)
+	}
+	if dest, err := fsys.Readlink("link"); err != nil || dest != "a" {
+		t.Errorf("Readlink(link) = %q, %v, want %q", dest, err, "a")
+	}
+}
+
+func TestToIOFS(t *testing.T) {
+	fsys := fs.ToIOFS(fs.MapFS{
+		"a":       {Data: []byte("hello")},
+		"b/c":     {Data: []byte("x")},
+		"b/d/e":   {},
+		"b/empty": {Mode: os.ModeDir | 0755},
+	})
+	if err := fstest.TestFS(fsys, "a", "b/c", "b/d/e", "b/empty"); err != nil {
+		t.Error(err)
+	}
+	matches, err := iofs.Glob(fsys, "b/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b/c", "b/d", "b/empty"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("Glob = %q, want %q", matches, want)
+	}
+}
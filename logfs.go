@@ -0,0 +1,100 @@
+package fs
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// logFS is a FileSystem that reports calls to the methods of
+// the embedded FileSystem that access files.
+type logFS struct {
+	FileSystem
+	log func(op, path string, err error, dur time.Duration)
+}
+
+// WithLogger returns a FileSystem that passes each method through
+// to fsys and, for each method that accesses files rather than
+// just manipulating paths, calls log once the method returns, with
+// the method's name, the path it was given, the error it returned,
+// and the time it took.
+func WithLogger(fsys FileSystem, log func(op, path string, err error, dur time.Duration)) FileSystem {
+	return &logFS{fsys, log}
+}
+
+func (l *logFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	t := time.Now()
+	list, err := l.FileSystem.ReadDir(dirname)
+	l.log("ReadDir", dirname, err, time.Since(t))
+	return list, err
+}
+
+func (l *logFS) ReadDirUnsorted(dirname string) ([]os.FileInfo, error) {
+	t := time.Now()
+	list, err := l.FileSystem.ReadDirUnsorted(dirname)
+	l.log("ReadDirUnsorted", dirname, err, time.Since(t))
+	return list, err
+}
+
+func (l *logFS) Lstat(name string) (os.FileInfo, error) {
+	t := time.Now()
+	info, err := l.FileSystem.Lstat(name)
+	l.log("Lstat", name, err, time.Since(t))
+	return info, err
+}
+
+func (l *logFS) Stat(name string) (os.FileInfo, error) {
+	t := time.Now()
+	info, err := l.FileSystem.Stat(name)
+	l.log("Stat", name, err, time.Since(t))
+	return info, err
+}
+
+func (l *logFS) Readlink(name string) (string, error) {
+	t := time.Now()
+	dst, err := l.FileSystem.Readlink(name)
+	l.log("Readlink", name, err, time.Since(t))
+	return dst, err
+}
+
+func (l *logFS) Open(name string) (io.ReadCloser, error) {
+	t := time.Now()
+	r, err := l.FileSystem.Open(name)
+	l.log("Open", name, err, time.Since(t))
+	return r, err
+}
+
+func (l *logFS) MkdirAll(path string, perm os.FileMode) error {
+	t := time.Now()
+	err := l.FileSystem.MkdirAll(path, perm)
+	l.log("MkdirAll", path, err, time.Since(t))
+	return err
+}
+
+func (l *logFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	t := time.Now()
+	err := l.FileSystem.WriteFile(name, data, perm)
+	l.log("WriteFile", name, err, time.Since(t))
+	return err
+}
+
+func (l *logFS) Glob(pattern string) ([]string, error) {
+	t := time.Now()
+	matches, err := l.FileSystem.Glob(pattern)
+	l.log("Glob", pattern, err, time.Since(t))
+	return matches, err
+}
+
+func (l *logFS) Remove(name string) error {
+	t := time.Now()
+	err := l.FileSystem.Remove(name)
+	l.log("Remove", name, err, time.Since(t))
+	return err
+}
+
+func (l *logFS) RemoveAll(path string) error {
+	t := time.Now()
+	err := l.FileSystem.RemoveAll(path)
+	l.log("RemoveAll", path, err, time.Since(t))
+	return err
+}
@@ -0,0 +1,40 @@
+package fs_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kr/fs"
+)
+
+func TestWithLogger(t *testing.T) {
+	var got []string
+	fsys := fs.WithLogger(fs.MapFS{"a": mapFile, "b/c": mapFile}, func(op, path string, err error, dur time.Duration) {
+		if err != nil {
+			op += " error"
+		}
+		got = append(got, op+" "+path)
+	})
+	fs.Collect(fs.WalkFS(".", fsys))
+	fsys.Open("missing")
+	fsys.MkdirAll("d", 0755)
+	fsys.WriteFile("a/e", nil, 0644)
+	fsys.Glob("b/*")
+	fsys.Remove("a")
+	fsys.RemoveAll("b")
+	want := []string{
+		"Lstat .",
+		"ReadDir .",
+		"ReadDir b",
+		"Open error missing",
+		"MkdirAll d",
+		"WriteFile error a/e",
+		"Glob b/*",
+		"Remove a",
+		"RemoveAll b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,279 @@
+package fs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MapFS is an in-memory FileSystem, useful in tests.
+// The map keys are slash-separated paths relative to the root,
+// which is named ".", and the values describe the files.
+// Directories that contain files in the map but are not
+// themselves in the map are synthesized.
+//
+// Paths given to the methods of MapFS are cleaned before use.
+// MkdirAll, WriteFile, Remove, and RemoveAll modify the map, so
+// a MapFS must not be modified while other goroutines use it.
+// The destination of a symbolic link is interpreted relative
+// to the directory containing the link. The root is also the
+// current directory, so Abs only cleans its argument.
+type MapFS map[string]*MapFile
+
+// A MapFile describes a single file in a MapFS.
+type MapFile struct {
+	Data    []byte      // file content, or symbolic link destination
+	Mode    os.FileMode // file mode bits
+	ModTime time.Time   // modification time
+}
+
+// maxLinks is the most symbolic links MapFS follows
+// while resolving a single path.
+const maxLinks = 255
+
+var errNotEmpty = errors.New("directory not empty")
+
+type mapInfo struct {
+	name string
+	f    *MapFile
+}
+
+func (fi *mapInfo) Name() string       { return fi.name }
+func (fi *mapInfo) Size() int64        { return int64(len(fi.f.Data)) }
+func (fi *mapInfo) Mode() os.FileMode  { return fi.f.Mode }
+func (fi *mapInfo) ModTime() time.Time { return fi.f.ModTime }
+func (fi *mapInfo) IsDir() bool        { return fi.f.Mode.IsDir() }
+func (fi *mapInfo) Sys() interface{}   { return fi.f }
+
+// lookup returns the file stored or synthesized at the clean path
+// name, without resolving any symbolic links.
+func (m MapFS) lookup(name string) *MapFile {
+	if f := m[name]; f != nil {
+		return f
+	}
+	if name == "." || m.hasEntries(name) {
+		return &MapFile{Mode: os.ModeDir | 0555}
+	}
+	return nil
+}
+
+// resolve returns the path of name with symbolic links resolved,
+// along with the file found there. The final element is resolved
+// only if follow is true.
+func (m MapFS) resolve(op, name string, follow bool) (string, *MapFile, error) {
+	elem := strings.Split(path.Clean(name), "/")
+	dir, links := ".", 0
+	for i := 0; i < len(elem); i++ {
+		p := path.Join(dir, elem[i])
+		f := m.lookup(p)
+		if f == nil {
+			return "", nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+		}
+		last := i == len(elem)-1
+		if f.Mode&os.ModeSymlink == 0 || last && !follow {
+			if last {
+				return p, f, nil
+			}
+			if !f.Mode.IsDir() {
+				return "", nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+			}
+			dir = p
+			continue
+		}
+		if links++; links > maxLinks {
+			return "", nil, &os.PathError{Op: op, Path: name, Err: os.ErrInvalid}
+		}
+		rest := append(strings.Split(path.Join(dir, string(f.Data)), "/"), elem[i+1:]...)
+		elem, dir, i = rest, ".", -1
+	}
+	return ".", m.lookup("."), nil
+}
+
+func (m MapFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	list, err := m.ReadDirUnsorted(dirname)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, err
+}
+
+func (m MapFS) ReadDirUnsorted(dirname string) ([]os.FileInfo, error) {
+	dir, f, err := m.resolve("readdir", dirname, true)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: dirname, Err: os.ErrInvalid}
+	}
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	seen := make(map[string]bool)
+	var list []os.FileInfo
+	for k := range m {
+		if !strings.HasPrefix(k, prefix) || k == dir {
+			continue
+		}
+		name := k[len(prefix):]
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name = name[:i]
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		list = append(list, &mapInfo{name, m.lookup(prefix + name)})
+	}
+	return list, nil
+}
+
+func (m MapFS) Lstat(name string) (os.FileInfo, error) {
+	_, f, err := m.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return &mapInfo{path.Base(path.Clean(name)), f}, nil
+}
+
+func (m MapFS) Stat(name string) (os.FileInfo, error) {
+	_, f, err := m.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &mapInfo{path.Base(path.Clean(name)), f}, nil
+}
+
+func (m MapFS) Readlink(name string) (string, error) {
+	_, f, err := m.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if f.Mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return string(f.Data), nil
+}
+
+func (m MapFS) Open(name string) (io.ReadCloser, error) {
+	_, f, err := m.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if f.Mode.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	return ioutil.NopCloser(bytes.NewReader(f.Data)), nil
+}
+
+func (m MapFS) MkdirAll(name string, perm os.FileMode) error {
+	if _, f, err := m.resolve("mkdir", name, true); err == nil {
+		if !f.Mode.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+		}
+		return nil
+	}
+	parent := path.Dir(path.Clean(name))
+	if err := m.MkdirAll(parent, perm); err != nil {
+		return err
+	}
+	dir, _, err := m.resolve("mkdir", parent, true)
+	if err != nil {
+		return err
+	}
+	p := path.Join(dir, path.Base(name))
+	if m.lookup(p) != nil {
+		// A dangling symbolic link.
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	m[p] = &MapFile{Mode: os.ModeDir | perm.Perm(), ModTime: time.Now()}
+	return nil
+}
+
+func (m MapFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	p, f, err := m.resolve("open", name, true)
+	if err != nil {
+		dir, d, err := m.resolve("open", path.Dir(path.Clean(name)), true)
+		if err != nil {
+			return err
+		}
+		p = path.Join(dir, path.Base(name))
+		if !d.Mode.IsDir() || m.lookup(p) != nil {
+			return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		f = &MapFile{Mode: perm.Perm()}
+	}
+	if f.Mode.IsDir() {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	// Replace rather than modify f, which the caller may share.
+	m[p] = &MapFile{
+		Data:    append([]byte(nil), data...),
+		Mode:    f.Mode,
+		ModTime: time.Now(),
+	}
+	return nil
+}
+
+func (m MapFS) Remove(name string) error {
+	p, f, err := m.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if p == "." {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrInvalid}
+	}
+	if f.Mode.IsDir() && m.hasEntries(p) {
+		return &os.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+	}
+	delete(m, p)
+	return nil
+}
+
+func (m MapFS) RemoveAll(name string) error {
+	p, _, err := m.resolve("removeall", name, false)
+	if err != nil {
+		return nil
+	}
+	if p == "." {
+		return &os.PathError{Op: "removeall", Path: name, Err: os.ErrInvalid}
+	}
+	delete(m, p)
+	for k := range m {
+		if strings.HasPrefix(k, p+"/") {
+			delete(m, k)
+		}
+	}
+	return nil
+}
+
+// hasEntries reports whether the directory at the clean path dir
+// contains anything.
+func (m MapFS) hasEntries(dir string) bool {
+	for k := range m {
+		if strings.HasPrefix(k, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MapFS) Join(elem ...string) string { return path.Join(elem...) }
+
+func (m MapFS) PathSeparator() byte { return '/' }
+
+func (m MapFS) Split(p string) (dir, file string) { return path.Split(p) }
+
+func (m MapFS) Base(p string) string { return path.Base(p) }
+
+func (m MapFS) Rel(basepath, targpath string) (string, error) {
+	return slashRel(basepath, targpath)
+}
+
+func (m MapFS) Abs(p string) (string, error) { return path.Clean(p), nil }
+
+func (m MapFS) Glob(pattern string) ([]string, error) { return slashGlob(m, pattern) }
@@ -0,0 +1,269 @@
+package fs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+var mapTree = fs.MapFS{
+	"a":        {Data: []byte("hello")},
+	"b/c":      {Data: []byte("x")},
+	"b/d/e":    {},
+	"b/empty":  {Mode: os.ModeDir | 0755},
+	"link":     {Data: []byte("b/d"), Mode: os.ModeSymlink | 0777},
+	"b/d/up":   {Data: []byte("../.."), Mode: os.ModeSymlink | 0777},
+	"b/d/loop": {Data: []byte("loop"), Mode: os.ModeSymlink | 0777},
+	"dangling": {Data: []byte("missing"), Mode: os.ModeSymlink | 0777},
+}
+
+func TestMapFSReadDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{".", []string{"a", "b", "dangling", "link"}},
+		{"b", []string{"c", "d", "empty"}},
+		{"b/empty", nil},
+		{"link", []string{"e", "loop", "up"}},
+		{"link/up/b/d", []string{"e", "loop", "up"}},
+	}
+	for _, test := range tests {
+		list, err := mapTree.ReadDir(test.dir)
+		if err != nil {
+			t.Errorf("ReadDir(%q): %v", test.dir, err)
+			continue
+		}
+		var got []string
+		for _, info := range list {
+			got = append(got, info.Name())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ReadDir(%q) = %q, want %q", test.dir, got, test.want)
+		}
+	}
+	if _, err := mapTree.ReadDir("a"); err == nil {
+		t.Errorf("ReadDir(%q) succeeded on a file", "a")
+	}
+}
+
+func TestMapFSStat(t *testing.T) {
+	tests := []struct {
+		name      string
+		lstatMode os.FileMode
+		statMode  os.FileMode
+		size      int64
+	}{
+		{".", os.ModeDir, os.ModeDir, 0},
+		{"a", 0, 0, 5},
+		{"b", os.ModeDir, os.ModeDir, 0},
+		{"link", os.ModeSymlink, os.ModeDir, 3},
+		{"link/up/a", 0, 0, 5},
+	}
+	for _, test := range tests {
+		info, err := mapTree.Lstat(test.name)
+		if err != nil {
+			t.Errorf("Lstat(%q): %v", test.name, err)
+			continue
+		}
+		if info.Mode().Type() != test.lstatMode || info.Size() != test.size {
+			t.Errorf("Lstat(%q) = %v %d, want %v %d", test.name,
+				info.Mode().Type(), info.Size(), test.lstatMode, test.size)
+		}
+		info, err = mapTree.Stat(test.name)
+		if err != nil {
+			t.Errorf("Stat(%q): %v", test.name, err)
+			continue
+		}
+		if info.Mode().Type() != test.statMode {
+			t.Errorf("Stat(%q) = %v, want %v", test.name, info.Mode().Type(), test.statMode)
+		}
+	}
+	for _, name := range []string{"missing", "a/b", "dangling", "link/loop"} {
+		if _, err := mapTree.Stat(name); err == nil {
+			t.Errorf("Stat(%q) succeeded", name)
+		}
+	}
+}
+
+func TestMapFSReadlinkOpen(t *testing.T) {
+	if dst, err := mapTree.Readlink("link"); err != nil || dst != "b/d" {
+		t.Errorf("Readlink = %q, %v, want %q", dst, err, "b/d")
+	}
+	if _, err := mapTree.Readlink("a"); err == nil {
+		t.Errorf("Readlink succeeded on a regular file")
+	}
+	r, err := mapTree.Open("link/up/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if b, _ := ioutil.ReadAll(r); string(b) != "hello" {
+		t.Errorf("read %q, want %q", b, "hello")
+	}
+	if _, err := mapTree.Open("b"); err == nil {
+		t.Errorf("Open succeeded on a directory")
+	}
+}
+
+func TestMapFSPaths(t *testing.T) {
+	if sep := mapTree.PathSeparator(); sep != '/' {
+		t.Errorf("PathSeparator() = %q, want '/'", sep)
+	}
+	if p := mapTree.Join("b", "d", "e"); p != "b/d/e" {
+		t.Errorf("Join = %q, want %q", p, "b/d/e")
+	}
+	if dir, file := mapTree.Split("b/d/e"); dir != "b/d/" || file != "e" {
+		t.Errorf("Split = %q, %q, want %q, %q", dir, file, "b/d/", "e")
+	}
+	if base := mapTree.Base("b/d/"); base != "d" {
+		t.Errorf("Base = %q, want %q", base, "d")
+	}
+}
+
+func TestMapFSAbs(t *testing.T) {
+	for p, want := range map[string]string{"a/../b/": "b", ".": ".", "/a": "/a"} {
+		if abs, err := mapTree.Abs(p); err != nil || abs != want {
+			t.Errorf("Abs(%q) = %q, %v, want %q", p, abs, err, want)
+		}
+	}
+}
+
+func TestMapFSRel(t *testing.T) {
+	tests := []struct {
+		base, targ, want string
+	}{
+		{".", "a", "a"},
+		{"a", "a", "."},
+		{"a", ".", ".."},
+		{"a/b", "a/c/d", "../c/d"},
+		{"a/b/", "a/b/c", "c"},
+		{"/a", "/b", "../b"},
+		{"/", "/a/b", "a/b"},
+		{"a", "/b", ""},
+		{"../a", "b", ""},
+	}
+	for _, test := range tests {
+		got, err := mapTree.Rel(test.base, test.targ)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("Rel(%q, %q) = %q, want error", test.base, test.targ, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("Rel(%q, %q) = %q, %v, want %q", test.base, test.targ, got, err, test.want)
+		}
+	}
+}
+
+func TestMapFSWrite(t *testing.T) {
+	m := fs.MapFS{
+		"a":    {Data: []byte("hello"), Mode: 0600},
+		"b/c":  {},
+		"link": {Data: []byte("b"), Mode: os.ModeSymlink | 0777},
+	}
+	for _, dir := range []string{"b", "link/d/e", "f"} {
+		if err := m.MkdirAll(dir, 0750); err != nil {
+			t.Errorf("MkdirAll(%q): %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"a", "a/g"} {
+		if err := m.MkdirAll(dir, 0750); err == nil {
+			t.Errorf("MkdirAll(%q) succeeded", dir)
+		}
+	}
+	for name, data := range map[string]string{"a": "bye", "link/d/e/h": "x", "f/i": "y"} {
+		if err := m.WriteFile(name, []byte(data), 0640); err != nil {
+			t.Errorf("WriteFile(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"b", "missing/j", "a/k"} {
+		if err := m.WriteFile(name, nil, 0640); err == nil {
+			t.Errorf("WriteFile(%q) succeeded", name)
+		}
+	}
+
+	var got []string
+	walker := fs.WalkFS(".", m)
+	for walker.Step() {
+		got = append(got, walker.Path()+" "+walker.Stat().Mode().String())
+	}
+	want := []string{
+		". dr-xr-xr-x",
+		"a -rw-------",
+		"b dr-xr-xr-x",
+		"b/c ----------",
+		"b/d drwxr-x---",
+		"b/d/e drwxr-x---",
+		"b/d/e/h -rw-r-----",
+		"f drwxr-x---",
+		"f/i -rw-r-----",
+		"link Lrwxrwxrwx",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+	for name, want := range map[string]string{"a": "bye", "b/d/e/h": "x"} {
+		if got := string(m[name].Data); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMapFSGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*", []string{"a", "b", "dangling", "link"}},
+		{"b/*", []string{"b/c", "b/d", "b/empty"}},
+		{"*/d/*", []string{"b/d/e", "b/d/loop", "b/d/up"}},
+		{"link/[e-l]*", []string{"link/e", "link/loop"}},
+		{"link/e", []string{"link/e"}},
+		{"missing", nil},
+		{"a/*", nil},
+	}
+	for _, test := range tests {
+		got, err := mapTree.Glob(test.pattern)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Glob(%q) = %q, %v, want %q", test.pattern, got, err, test.want)
+		}
+	}
+	if _, err := mapTree.Glob("b/["); err != path.ErrBadPattern {
+		t.Errorf("Glob(%q): %v, want %v", "b/[", err, path.ErrBadPattern)
+	}
+}
+
+func TestMapFSRemove(t *testing.T) {
+	m := fs.MapFS{
+		"a":     mapFile,
+		"b/c":   mapFile,
+		"b/d/e": mapFile,
+		"b/f":   mapDir,
+		"link":  {Data: []byte("b"), Mode: os.ModeSymlink | 0777},
+	}
+	for _, name := range []string{"missing", ".", "b", "b/d"} {
+		if err := m.Remove(name); err == nil {
+			t.Errorf("Remove(%q) succeeded", name)
+		}
+	}
+	for _, name := range []string{"a", "b/f", "link"} {
+		if err := m.Remove(name); err != nil {
+			t.Errorf("Remove(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"missing", "b/d"} {
+		if err := m.RemoveAll(name); err != nil {
+			t.Errorf("RemoveAll(%q): %v", name, err)
+		}
+	}
+	if want := (fs.MapFS{"b/c": mapFile}); !reflect.DeepEqual(m, want) {
+		t.Errorf("after removing, m = %v, want %v", m, want)
+	}
+
+}
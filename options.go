@@ -0,0 +1,207 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// An Option configures a Walker made by Walk. Each Option is the
+// counterpart of a constructor such as WalkFollow or a method such
+// as SetFilter, and does the same. Options are applied in order.
+//
+// Options for different settings combine: with WithMaxDepth and
+// WithFilter, say, an entry is visited only if it is within the
+// maximum depth and the filter accepts it. Given an Option more
+// than once, the last one wins, and so do WithPostOrder, WithBFS,
+// and WithParallel, which each choose the order of the walk. The
+// exceptions are the Options that choose entries by their paths or
+// info, such as WithGlob, WithFiles, and WithIgnore: every one given
+// must accept an entry for it to be visited.
+type Option func(*Walker)
+
+// WithFileSystem makes the Walker walk fsys instead of the
+// FileSystem provided by the os package, as WalkFS does.
+func WithFileSystem(fsys FileSystem) Option {
+	return func(w *Walker) { w.fs = fsys }
+}
+
+// WithMaxDepth sets the maximum depth of the walk, as SetMaxDepth does.
+func WithMaxDepth(n int) Option {
+	return func(w *Walker) { w.SetMaxDepth(n) }
+}
+
+// WithFilter sets a function that decides which entries are
+// visited, as SetFilter does.
+func WithFilter(f func(path string, info os.FileInfo) bool) Option {
+	return func(w *Walker) { w.SetFilter(f) }
+}
+
+// WithMinDepth sets the minimum depth of entries visited, as
+// SetMinDepth does.
+func WithMinDepth(n int) Option {
+	return func(w *Walker) { w.SetMinDepth(n) }
+}
+
+// WithSortFunc sets the order of the entries of each directory, as
+// SetSortFunc does.
+func WithSortFunc(less func(a, b os.FileInfo) bool) Option {
+	return func(w *Walker) { w.SetSortFunc(less) }
+}
+
+// WithErrorHandler sets a function that decides how errors are
+// handled, as SetErrorHandler does.
+func WithErrorHandler(h func(path string, err error) error) Option {
+	return func(w *Walker) { w.SetErrorHandler(h) }
+}
+
+// WithContext makes the Walker stop once ctx is done, as
+// WalkContext does.
+func WithContext(ctx context.Context) Option {
+	return func(w *Walker) { w.ctx = ctx }
+}
+
+// WithTimeout makes the Walker stop once d has elapsed from the
+// call to Walk or Reset, as WalkTimeout does.
+func WithTimeout(d time.Duration) Option {
+	return func(w *Walker) { w.timed, w.timeout = true, d }
+}
+
+// WithLimit makes the Walker visit at most n entries, as WalkN does.
+func WithLimit(n int) Option {
+	return func(w *Walker) { w.limit = n }
+}
+
+// WithFollow makes the Walker follow symbolic links to directories,
+// as WalkFollow does.
+func WithFollow() Option {
+	return func(w *Walker) { w.follow, w.followMax = true, -1 }
+}
+
+// WithFollowDepth makes the Walker follow symbolic links to
+// directories at depth n or less, as WalkFollowDepth does.
+func WithFollowDepth(n int) Option {
+	return func(w *Walker) { w.follow, w.followMax = true, n }
+}
+
+// WithErrors makes the Walker record the errors it visits, for
+// Errors, as WalkErrors does.
+func WithErrors() Option {
+	return func(w *Walker) { w.collect = true }
+}
+
+// WithPostOrder makes the Walker visit the entries of each directory
+// before the directory, as WalkPostOrder does.
+func WithPostOrder() Option {
+	return func(w *Walker) { w.post, w.bfs, w.par = true, false, nil }
+}
+
+// WithBFS makes the Walker visit entries breadth-first, as WalkBFS
+// does.
+func WithBFS() Option {
+	return func(w *Walker) { w.post, w.bfs, w.par = false, true, nil }
+}
+
+// WithParallel makes the Walker read up to workers directories at
+// once, as WalkParallel does.
+func WithParallel(workers int) Option {
+	return func(w *Walker) { w.post, w.bfs, w.par = false, false, newParallel(workers) }
+}
+
+// WithOrdered makes the Walker visit the subdirectories of each
+// directory before or after its other entries, as WalkOrdered does.
+func WithOrdered(dirsFirst bool) Option {
+	return WithSortFunc(func(a, b os.FileInfo) bool {
+		return a.IsDir() == dirsFirst && b.IsDir() != dirsFirst
+	})
+}
+
+// WithShallow makes the Walker descend only into the directories
+// for which Descend is called, as WalkShallow does.
+func WithShallow() Option {
+	return func(w *Walker) { w.shallow = true }
+}
+
+// WithUnordered makes the Walker read directories without sorting
+// them, as WalkUnordered does.
+func WithUnordered() Option {
+	return func(w *Walker) { w.unordered = true }
+}
+
+// WithAbs makes the Walker walk the absolute form of each root, as
+// returned by the FileSystem's Abs, as WalkAbs does.
+func WithAbs() Option {
+	return func(w *Walker) { w.abs = true }
+}
+
+// WithFrom makes the Walker resume a walk after the entry at path
+// after, as WalkFrom does. Given more than once, the last one wins.
+func WithFrom(after string) Option {
+	return func(w *Walker) { w.from = &after }
+}
+
+// WithFiles makes the Walker visit every entry but the directories,
+// as WalkFiles does.
+func WithFiles() Option {
+	return func(w *Walker) {
+		w.rules = append(w.rules, func(_ *Walker, it *item) (visit, descend bool) {
+			return !it.info.IsDir(), true
+		})
+	}
+}
+
+// WithDirs makes the Walker visit only the directories, as WalkDirs
+// does.
+func WithDirs() Option {
+	return func(w *Walker) {
+		w.rules = append(w.rules, func(_ *Walker, it *item) (visit, descend bool) {
+			return it.info.IsDir(), it.info.IsDir()
+		})
+	}
+}
+
+// WithSince makes the Walker visit only the entries modified after
+// t, as WalkSince does.
+func WithSince(t time.Time) Option {
+	return func(w *Walker) {
+		w.rules = append(w.rules, func(_ *Walker, it *item) (visit, descend bool) {
+			return it.info.ModTime().After(t), true
+		})
+	}
+}
+
+// WithSkipHidden makes the Walker pass over hidden entries, as
+// SkipHidden does.
+func WithSkipHidden() Option {
+	return func(w *Walker) { w.SkipHidden(true) }
+}
+
+// WithDirFilter sets a function that decides which directories are
+// descended into, as SetDirFilter does.
+func WithDirFilter(f func(path string, info os.FileInfo) bool) Option {
+	return func(w *Walker) { w.SetDirFilter(f) }
+}
+
+// WithDetectCycles makes the Walker check each directory against
+// those containing it, as DetectCycles does.
+func WithDetectCycles() Option {
+	return func(w *Walker) { w.DetectCycles(true) }
+}
+
+// WithIgnoreMissingRoot makes the Walker pass over roots that do
+// not exist, as IgnoreMissingRoot does.
+func WithIgnoreMissingRoot() Option {
+	return func(w *Walker) { w.IgnoreMissingRoot(true) }
+}
+
+// WithProgress sets a function to report the progress of the walk,
+// as SetProgress does.
+func WithProgress(every int, fn func(count int, lastPath string)) Option {
+	return func(w *Walker) { w.SetProgress(every, fn) }
+}
+
+// WithReadDirBatch makes the Walker read directories n entries at
+// a time, as SetReadDirBatch does.
+func WithReadDirBatch(n int) Option {
+	return func(w *Walker) { w.SetReadDirBatch(n) }
+}
@@ -0,0 +1,218 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr/fs"
+)
+
+func TestWalkOptions(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":     mapFile,
+		"r/b.tmp": mapFile,
+		"r/c/d":   mapFile,
+		"r/c/e/f": mapFile,
+	}
+	walker := fs.Walk("r",
+		fs.WithFileSystem(m),
+		fs.WithMaxDepth(2),
+		fs.WithFilter(func(path string, info os.FileInfo) bool {
+			return !strings.HasSuffix(path, ".tmp")
+		}),
+	)
+	var got []string
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, walker.Path())
+	}
+	want := []string{"r", "r/a", "r/c", "r/c/d", "r/c/e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkOptionsCompose(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":     mapFile,
+		"r/b/c":   mapFile,
+		"r/b/d/e": mapFile,
+		"r/f":     mapFile,
+	}
+	walk := func(opts ...fs.Option) []string {
+		var got []string
+		walker := fs.Walk("r", append([]fs.Option{fs.WithFileSystem(m)}, opts...)...)
+		for walker.Step() {
+			got = append(got, walker.Path())
+		}
+		return got
+	}
+	tests := []struct {
+		name string
+		opts []fs.Option
+		want []string
+	}{
+		{"none", nil, []string{"r", "r/a", "r/b", "r/b/c", "r/b/d", "r/b/d/e", "r/f"}},
+		{"depth", []fs.Option{fs.WithMinDepth(1), fs.WithMaxDepth(2)},
+			[]string{"r/a", "r/b", "r/b/c", "r/b/d", "r/f"}},
+		{"post", []fs.Option{fs.WithMaxDepth(1), fs.WithPostOrder()},
+			[]string{"r/a", "r/b", "r/f", "r"}},
+		{"bfs", []fs.Option{fs.WithPostOrder(), fs.WithBFS()},
+			[]string{"r", "r/a", "r/b", "r/f", "r/b/c", "r/b/d", "r/b/d/e"}},
+		{"limit", []fs.Option{fs.WithLimit(5), fs.WithLimit(2)}, []string{"r", "r/a"}},
+		{"sort", []fs.Option{fs.WithMaxDepth(1), fs.WithSortFunc(func(a, b os.FileInfo) bool {
+			return a.Name() > b.Name()
+		})}, []string{"r", "r/f", "r/b", "r/a"}},
+	}
+	for _, test := range tests {
+		if got := walk(test.opts...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: walk = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	var got []string
+	walker := fs.Walk("r", fs.WithFileSystem(m), fs.WithParallel(2))
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	if len(got) != 7 {
+		t.Errorf("parallel walk visited %q, want 7 entries", got)
+	}
+}
+
+func TestWalkModeOptions(t *testing.T) {
+	m := fs.MapFS{
+		"r/.gitignore": {Data: []byte("*.o\n")},
+		"r/.h":         mapFile,
+		"r/a.go":       mapFile,
+		"r/a.o":        mapFile,
+		"r/b/c.go":     mapFile,
+		"r/b/d/e":      mapFile,
+	}
+	tests := []struct {
+		name string
+		opt  fs.Option
+		want []string
+	}{
+		{"glob", fs.WithGlob("**/*.go"), []string{"r/a.go", "r/b/c.go"}},
+		{"gitignore", fs.WithGitIgnore(),
+			[]string{"r", "r/.gitignore", "r/.h", "r/a.go", "r/b", "r/b/c.go", "r/b/d", "r/b/d/e"}},
+		{"ignore", fs.WithIgnore([]string{"*.o", "d/"}),
+			[]string{"r", "r/.gitignore", "r/.h", "r/a.go", "r/b", "r/b/c.go"}},
+		{"from", fs.WithFrom("r/b/c.go"), []string{"r/b/d", "r/b/d/e"}},
+		{"files", fs.WithFiles(),
+			[]string{"r/.gitignore", "r/.h", "r/a.go", "r/a.o", "r/b/c.go", "r/b/d/e"}},
+		{"dirs", fs.WithDirs(), []string{"r", "r/b", "r/b/d"}},
+		{"since", fs.WithSince(time.Time{}), nil},
+		{"shallow", fs.WithShallow(), []string{"r"}},
+		{"hidden", fs.WithSkipHidden(),
+			[]string{"r", "r/a.go", "r/a.o", "r/b", "r/b/c.go", "r/b/d", "r/b/d/e"}},
+		{"dirfilter", fs.WithDirFilter(func(path string, info os.FileInfo) bool {
+			return info.Name() != "b"
+		}), []string{"r", "r/.gitignore", "r/.h", "r/a.go", "r/a.o", "r/b"}},
+		{"ordered", fs.WithOrdered(true),
+			[]string{"r", "r/b", "r/b/d", "r/b/d/e", "r/b/c.go", "r/.gitignore", "r/.h", "r/a.go", "r/a.o"}},
+		{"abs", fs.WithAbs(),
+			[]string{"r", "r/.gitignore", "r/.h", "r/a.go", "r/a.o", "r/b", "r/b/c.go", "r/b/d", "r/b/d/e"}},
+	}
+	for _, test := range tests {
+		var got []string
+		walker := fs.Walk("r/b/..", fs.WithFileSystem(m), test.opt)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			got = append(got, path.Clean(walker.Path()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: walk = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	walker := fs.Walk("r/b/..", fs.WithFileSystem(m), fs.WithAbs())
+	if walker.Step(); walker.Path() != "r" {
+		t.Errorf("WithAbs: root Path() = %q, want %q", walker.Path(), "r")
+	}
+	if walker.Root() != "r" {
+		t.Errorf("WithAbs: Root() = %q, want %q", walker.Root(), "r")
+	}
+}
+
+func TestWalkFollowCycles(t *testing.T) {
+	m := fs.MapFS{
+		"r/l1":   {Data: []byte("."), Mode: os.ModeSymlink | 0777},
+		"r/l2":   {Data: []byte("x"), Mode: os.ModeSymlink | 0777},
+		"r/x/up": {Data: []byte("../l1"), Mode: os.ModeSymlink | 0777},
+		"r/x/y":  mapFile,
+	}
+	for _, opt := range []fs.Option{fs.WithFollow(), fs.WithDetectCycles()} {
+		var got []string
+		walker := fs.Walk("r", fs.WithFileSystem(m), fs.WithFollow(), opt, fs.WithLimit(100))
+		for walker.Step() {
+			s := walker.Path()
+			if err := walker.Err(); err != nil {
+				if !errors.Is(err, fs.ErrCycle) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				s = "cycle " + s
+			}
+			got = append(got, s)
+		}
+		want := []string{
+			"r",
+			"cycle r/l1",
+			"r/l2",
+			"cycle r/l2/up",
+			"r/l2/y",
+			"r/x",
+			"cycle r/x/up",
+			"r/x/y",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("walk = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestWalkFromOrders(t *testing.T) {
+	m := fs.MapFS{
+		"r/a/x": mapFile,
+		"r/b":   mapFile,
+		"r/c/y": mapFile,
+		"r/d":   mapFile,
+	}
+	tests := []struct {
+		name string
+		opts []fs.Option
+		want []string
+	}{
+		{"walk", []fs.Option{fs.WithFrom("r/c/y")}, []string{"r/d"}},
+		{"ordered", []fs.Option{fs.WithOrdered(true), fs.WithFrom("r/c/y")}, []string{"r/b", "r/d"}},
+		{"bfs", []fs.Option{fs.WithBFS(), fs.WithFrom("r/b")},
+			[]string{"r/c", "r/d", "r/a/x", "r/c/y"}},
+		{"bfs after", []fs.Option{fs.WithFrom("r/b"), fs.WithBFS()},
+			[]string{"r/c", "r/d", "r/a/x", "r/c/y"}},
+		{"post", []fs.Option{fs.WithPostOrder(), fs.WithFrom("r/a")},
+			[]string{"r/b", "r/c/y", "r/c", "r/d", "r"}},
+	}
+	for _, test := range tests {
+		var got []string
+		walker := fs.Walk("r", append([]fs.Option{fs.WithFileSystem(m)}, test.opts...)...)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			got = append(got, walker.Path())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: walk = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
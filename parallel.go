@@ -0,0 +1,92 @@
+package fs
+
+import (
+	"os"
+)
+
+// parallel holds the state of a Walker created by WalkParallel.
+// Directories are queued for reading when Step is about to descend
+// into them, and read by up to workers goroutines at once.
+type parallel struct {
+	workers  int
+	queue    []item
+	inflight int
+
+	// results has room for every read in flight, so the reading
+	// goroutines never block, even if the Walker is abandoned.
+	results chan readResult
+}
+
+type readResult struct {
+	dir  item
+	list []os.FileInfo
+	err  error
+}
+
+// WalkParallel returns a new Walker rooted at root that reads up to
+// workers directories concurrently, which can be much faster on slow
+// or networked storage. Entries are visited in no particular order,
+// though each directory is still visited before its entries, and
+// SkipDir behaves as for Walk.
+//
+// Since workers bounds the reads in progress, it also bounds the
+// directories open at once, where a Walker that is not parallel has
+// at most one open, and only while it reads it, unless it reads
+// directories in batches, as set by SetReadDirBatch. To walk in
+// parallel on a system with few file descriptors to spare, choose
+// workers accordingly.
+func WalkParallel(root string, workers int) *Walker {
+	w := Walk(root)
+	w.par = newParallel(workers)
+	return w
+}
+
+func newParallel(workers int) *parallel {
+	if workers < 1 {
+		workers = 1
+	}
+	return &parallel{
+		workers: workers,
+		results: make(chan readResult, workers),
+	}
+}
+
+// wait starts reading queued directories and pushes the entries of
+// those that are done onto the stack of w. It blocks until there is
+// something on the stack or nothing is left to read.
+func (p *parallel) wait(w *Walker) {
+	for {
+		for p.inflight < p.workers && len(p.queue) > 0 {
+			dir := p.queue[0]
+			p.queue = p.queue[1:]
+			p.inflight++
+			go func() {
+				list, err := w.readDir(dir.path)
+				p.results <- readResult{dir, list, err}
+			}()
+		}
+		if p.inflight == 0 {
+			return
+		}
+		var r readResult
+		if len(w.stack) > 0 {
+			select {
+			case r = <-p.results:
+			default:
+				return
+			}
+		} else {
+			r = <-p.results
+		}
+		p.inflight--
+		w.push(r.dir, r.list, r.err)
+	}
+}
+
+// drain waits for the reads in flight to finish,
+// discarding their results.
+func (p *parallel) drain() {
+	for ; p.inflight > 0; p.inflight-- {
+		<-p.results
+	}
+}
@@ -0,0 +1,43 @@
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrReadOnly is returned by the methods of a FileSystem made
+// by ReadOnly or FromIOFS that would modify the file system.
+var ErrReadOnly = errors.New("fs: read-only file system")
+
+// readOnly is a FileSystem that refuses to be modified.
+// Each method that modifies a file system must be overridden
+// here, so that it does not reach the embedded FileSystem.
+type readOnly struct {
+	FileSystem
+}
+
+// ReadOnly returns a FileSystem that passes the methods that
+// read fsys through to it, and returns ErrReadOnly, wrapped
+// in an *os.PathError, from any method that would modify it.
+func ReadOnly(fsys FileSystem) FileSystem {
+	if ro, ok := fsys.(*readOnly); ok {
+		return ro
+	}
+	return &readOnly{fsys}
+}
+
+func (ro *readOnly) MkdirAll(path string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: path, Err: ErrReadOnly}
+}
+
+func (ro *readOnly) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+}
+
+func (ro *readOnly) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+func (ro *readOnly) RemoveAll(path string) error {
+	return &os.PathError{Op: "removeall", Path: path, Err: ErrReadOnly}
+}
@@ -0,0 +1,37 @@
+package fs_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestReadOnly(t *testing.T) {
+	m := fs.MapFS{"a": mapFile, "b/c": mapFile}
+	ro := fs.ReadOnly(m)
+	want, _ := fs.Collect(fs.WalkFS(".", m))
+	got, _ := fs.Collect(fs.WalkFS(".", ro))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %v, want %v", got, want)
+	}
+	if fs.ReadOnly(ro) != ro {
+		t.Errorf("ReadOnly wrapped a read-only FileSystem again")
+	}
+	if err := ro.WriteFile("a", []byte("x"), 0644); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("WriteFile: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if err := ro.MkdirAll("d/e", 0755); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("MkdirAll: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if err := ro.Remove("a"); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("Remove: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if err := ro.RemoveAll("b"); !errors.Is(err, fs.ErrReadOnly) {
+		t.Errorf("RemoveAll: %v, want %v", err, fs.ErrReadOnly)
+	}
+	if len(m) != 2 || len(m["a"].Data) != 0 {
+		t.Errorf("read-only FileSystem was modified: %v", m)
+	}
+}
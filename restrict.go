@@ -0,0 +1,174 @@
+package fs
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// restricted is a FileSystem that hides the paths not allowed.
+// Each method that accesses files must be overridden here, so
+// that it does not reach the embedded FileSystem unchecked.
+type restricted struct {
+	FileSystem
+	allow func(path string) bool
+}
+
+// Restrict returns a FileSystem that passes the methods of fsys
+// through to it, but only for the paths that allow reports true
+// for. The rest appear not to exist: ReadDir and Glob leave them
+// out, and the other methods that access files fail with an
+// *os.PathError wrapping os.ErrNotExist.
+//
+// Paths are cleaned before they are checked, and a path is allowed
+// only if the path it leads to, with symbolic links resolved, is
+// allowed too, so neither ".." nor a link can reach a path that is
+// not allowed. Resolving links costs a call to Lstat for each
+// element of each path. RemoveAll removes only what is allowed: if
+// anything hidden is left in a directory, the directory is kept,
+// and RemoveAll returns the error from removing it.
+func Restrict(fsys FileSystem, allow func(path string) bool) FileSystem {
+	return &restricted{fsys, allow}
+}
+
+// check returns name cleaned, or an error if it is not allowed.
+// The final element of name is resolved, if it is a symbolic
+// link, only if follow is true.
+func (r *restricted) check(op, name string, follow bool) (string, error) {
+	clean := r.Join(name)
+	if clean == "" {
+		clean = "."
+	}
+	// An error resolving links is left for fsys to report.
+	resolved, _ := resolveLinks(r.FileSystem, clean, follow)
+	if !r.allow(clean) || !r.allow(resolved) {
+		return "", &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return clean, nil
+}
+
+// filter returns the entries of list, read from dirname, that
+// are allowed.
+func (r *restricted) filter(dirname string, list []os.FileInfo) []os.FileInfo {
+	dir, _ := resolveLinks(r.FileSystem, dirname, true)
+	var ok []os.FileInfo
+	for _, info := range list {
+		if r.allow(r.Join(dirname, info.Name())) && r.allow(r.Join(dir, info.Name())) {
+			ok = append(ok, info)
+		}
+	}
+	return ok
+}
+
+func (r *restricted) ReadDir(dirname string) ([]os.FileInfo, error) {
+	dirname, err := r.check("readdir", dirname, true)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.FileSystem.ReadDir(dirname)
+	return r.filter(dirname, list), err
+}
+
+func (r *restricted) ReadDirUnsorted(dirname string) ([]os.FileInfo, error) {
+	dirname, err := r.check("readdir", dirname, true)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.FileSystem.ReadDirUnsorted(dirname)
+	return r.filter(dirname, list), err
+}
+
+func (r *restricted) Lstat(name string) (os.FileInfo, error) {
+	name, err := r.check("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return r.FileSystem.Lstat(name)
+}
+
+func (r *restricted) Stat(name string) (os.FileInfo, error) {
+	name, err := r.check("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return r.FileSystem.Stat(name)
+}
+
+func (r *restricted) Readlink(name string) (string, error) {
+	name, err := r.check("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	return r.FileSystem.Readlink(name)
+}
+
+func (r *restricted) Open(name string) (io.ReadCloser, error) {
+	name, err := r.check("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return r.FileSystem.Open(name)
+}
+
+func (r *restricted) MkdirAll(path string, perm os.FileMode) error {
+	path, err := r.check("mkdir", path, true)
+	if err != nil {
+		return err
+	}
+	return r.FileSystem.MkdirAll(path, perm)
+}
+
+func (r *restricted) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name, err := r.check("open", name, true)
+	if err != nil {
+		return err
+	}
+	return r.FileSystem.WriteFile(name, data, perm)
+}
+
+func (r *restricted) Remove(name string) error {
+	name, err := r.check("remove", name, false)
+	if err != nil {
+		return err
+	}
+	return r.FileSystem.Remove(name)
+}
+
+// RemoveAll removes path and the allowed entries beneath it,
+// one at a time, so that nothing hidden is removed.
+func (r *restricted) RemoveAll(path string) error {
+	path, err := r.check("removeall", path, false)
+	if err != nil {
+		return err
+	}
+	info, err := r.FileSystem.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		list, err := r.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range list {
+			if err := r.RemoveAll(r.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return r.FileSystem.Remove(path)
+}
+
+func (r *restricted) Glob(pattern string) ([]string, error) {
+	matches, err := r.FileSystem.Glob(pattern)
+	var ok []string
+	for _, m := range matches {
+		if _, err := r.check("glob", m, false); err == nil {
+			ok = append(ok, m)
+		}
+	}
+	return ok, err
+}
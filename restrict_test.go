@@ -0,0 +1,76 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestRestrict(t *testing.T) {
+	m := fs.MapFS{
+		"a":            mapFile,
+		"b/c":          mapFile,
+		"secret/key":   mapFile,
+		"b/secret.txt": mapFile,
+		"b/link":       &fs.MapFile{Data: []byte("../secret/key"), Mode: os.ModeSymlink | 0777},
+	}
+	fsys := fs.Restrict(m, func(path string) bool {
+		return !strings.Contains(path, "secret")
+	})
+	var got []string
+	walker := fs.WalkFS(".", fsys)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, walker.Path())
+	}
+	want := []string{".", "a", "b", "b/c", "b/link"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	if matches, err := fsys.Glob("*/*"); err != nil || !reflect.DeepEqual(matches, []string{"b/c", "b/link"}) {
+		t.Errorf("Glob = %q, %v, want %q", matches, err, []string{"b/c", "b/link"})
+	}
+	if _, err := fsys.Stat("secret/key"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat of hidden path: %v, want not exist", err)
+	}
+	if _, err := fsys.Open("secret/key"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open of hidden path: %v, want not exist", err)
+	}
+	if _, err := fsys.Stat("b/../secret/key"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat of hidden path through ..: %v, want not exist", err)
+	}
+	if _, err := fsys.Stat("b/link"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat through link to hidden path: %v, want not exist", err)
+	}
+	if _, err := fsys.Open("b/link"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open through link to hidden path: %v, want not exist", err)
+	}
+	if _, err := fsys.Lstat("b/link"); err != nil {
+		t.Errorf("Lstat of allowed link: %v", err)
+	}
+	if err := fsys.RemoveAll("secret"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RemoveAll of hidden path: %v, want not exist", err)
+	}
+	if err := fsys.WriteFile("b/secret.txt", nil, 0644); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("WriteFile of hidden path: %v, want not exist", err)
+	}
+	if m["secret/key"] == nil || m["b/secret.txt"] != mapFile {
+		t.Errorf("hidden files were modified")
+	}
+	if err := fsys.Remove("a"); err != nil || m["a"] != nil {
+		t.Errorf("Remove of allowed path: %v", err)
+	}
+	if err := fsys.RemoveAll("b"); err == nil {
+		t.Errorf("RemoveAll of directory with hidden entries: no error")
+	}
+	if m["b/secret.txt"] != mapFile || m["b/c"] != nil || m["b/link"] != nil {
+		t.Errorf("RemoveAll of b: removed %v, want b/c and b/link only", m)
+	}
+}
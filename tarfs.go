@@ -0,0 +1,58 @@
+package fs
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// TarFS reads the tar archive from r and returns a FileSystem that
+// presents its entries as a tree, as a MapFS does: directories implied
+// by the names in the archive are synthesized, symbolic links are
+// resolved by Stat and Open, and paths are slash-separated, with the
+// root named ".". The mode of each entry, including its type, is that
+// reported by the FileInfo method of its header.
+//
+// The whole archive is read into memory. A hard link gets a copy of
+// the content of its target, which must come earlier in the archive.
+// If a name occurs more than once, the last entry wins, as it would
+// when extracting the archive.
+func TarFS(r io.Reader) (FileSystem, error) {
+	m := make(MapFS)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := tarName(hdr.Name)
+		if name == "." {
+			continue
+		}
+		f := &MapFile{Mode: hdr.FileInfo().Mode(), ModTime: hdr.ModTime}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			f.Data = []byte(hdr.Linkname)
+		case tar.TypeLink:
+			if t := m[tarName(hdr.Linkname)]; t != nil {
+				f.Data, f.Mode = t.Data, t.Mode
+			}
+		default:
+			if f.Data, err = ioutil.ReadAll(tr); err != nil {
+				return nil, err
+			}
+		}
+		m[name] = f
+	}
+}
+
+// tarName returns the clean path, relative to the root, of the
+// archive member name.
+func tarName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
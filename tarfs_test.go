@@ -0,0 +1,70 @@
+package fs_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestTarFS(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "./a", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		{Name: "b/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "b/c/d", Typeflag: tar.TypeReg, Mode: 0600, Size: 5},
+		{Name: "b/hard", Typeflag: tar.TypeLink, Linkname: "a"},
+		{Name: "b/link", Typeflag: tar.TypeSymlink, Linkname: "c", Mode: 0777},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("hello")[:hdr.Size])
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := fs.TarFS(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	walker := fs.WalkFS(".", fsys)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, walker.Path()+" "+walker.Stat().Mode().String())
+	}
+	want := []string{
+		". dr-xr-xr-x",
+		"a -rw-r--r--",
+		"b drwxr-xr-x",
+		"b/c dr-xr-xr-x",
+		"b/c/d -rw-------",
+		"b/hard -rw-r--r--",
+		"b/link Lrwxrwxrwx",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"a", "b/hard", "b/link/d"} {
+		r, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := ioutil.ReadAll(r); string(b) != "hello" {
+			t.Errorf("read %q from %s, want %q", b, name, "hello")
+		}
+		r.Close()
+	}
+	if _, err := fs.TarFS(bytes.NewReader([]byte("not a tar file"))); err == nil {
+		t.Errorf("TarFS succeeded on a malformed archive")
+	}
+}
@@ -0,0 +1,33 @@
+package fs
+
+// UniqueFiles walks the tree rooted at root, as Walk does, and
+// returns the path of every entry other than a directory, except
+// that of several hard links to the same file only the first
+// visited is returned. Symbolic links are not followed.
+// If any entry has an error, UniqueFiles stops and returns it.
+//
+// Hard links are recognized by the device and inode numbers in
+// the *syscall.Stat_t returned by the Sys method of each entry's
+// FileInfo, so only on Unix systems. Elsewhere, or when Sys returns
+// anything else, every entry is taken to be a distinct file.
+func UniqueFiles(root string) ([]string, error) {
+	w := Walk(root)
+	seen := make(map[fileID]bool)
+	var paths []string
+	for w.Step() {
+		if err := w.Err(); err != nil {
+			return nil, err
+		}
+		if w.IsDir() {
+			continue
+		}
+		if id, ok := fileIDOf(w.Stat()); ok {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		paths = append(paths, w.Path())
+	}
+	return paths, nil
+}
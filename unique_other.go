@@ -0,0 +1,12 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package fs
+
+import "os"
+
+type fileID struct{}
+
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,58 @@
+package fs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestUniqueFiles(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows", "plan9", "js":
+		t.Skipf("hard links not recognized on %s", runtime.GOOS)
+	}
+	root := t.TempDir()
+	for _, name := range []string{"b/c", "d"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for old, link := range map[string]string{"b/c": "a", "d": "e/f"} {
+		p := filepath.Join(root, filepath.FromSlash(link))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Link(filepath.Join(root, filepath.FromSlash(old)), p); err != nil {
+			t.Skipf("hard links not supported: %v", err)
+		}
+	}
+	if err := os.Symlink("d", filepath.Join(root, "g")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fs.UniqueFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(root, "a"),
+		filepath.Join(root, "d"),
+		filepath.Join(root, "g"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UniqueFiles = %q, want %q", got, want)
+	}
+
+	if _, err := fs.UniqueFiles(filepath.Join(root, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("UniqueFiles of missing root: %v, want not exist", err)
+	}
+}
@@ -2,7 +2,15 @@
 package fs
 
 import (
+	"context"
+	"errors"
+	"io"
+	iofs "io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Walker provides a convenient interface for iterating over the
@@ -11,32 +19,528 @@ import (
 // file or directory in the tree, including the root. The files
 // are walked in lexical order, which makes the output deterministic
 // but means that for very large directories Walker can be inefficient.
-// Walker does not follow symbolic links.
+// SetSortFunc can change the order, and SetReadDirBatch can give
+// it up to save memory.
+// Walker does not follow symbolic links, unless created by WalkFollow.
+// A root that is not a directory is the only entry of its walk,
+// at depth 0; IsSingleFile reports such a root.
+//
+// A Walker must be used by one goroutine at a time. Its accessors
+// describe the most recent entry, and change with each Step; to hand
+// an entry to another goroutine, pass it the value returned by Entry.
 type Walker struct {
-	fs      FileSystem
+	fs         FileSystem
+	cur        item
+	stack      []item
+	descend    bool
+	maxDepth   int
+	minDepth   int
+	skipHidden bool
+	missingOK  bool // pass over roots that do not exist
+	ctx        context.Context
+	timed      bool          // whether to give up after timeout
+	timeout    time.Duration // from the start of each walk
+	deadline   time.Time     // when to give up, if timed
+	follow     bool
+	followMax  int  // depth of the deepest links to follow, or -1
+	cycles     bool // check every directory against its ancestors
+	abs        bool // make roots absolute
+	filter     func(path string, info os.FileInfo) bool
+	dirFilter  func(path string, info os.FileInfo) bool
+	collect    bool
+	errs       []error
+	par        *parallel
+	post       bool // report directories after their entries
+	bfs        bool // stack is a FIFO queue
+	shallow    bool // descend only when asked to
+	unordered  bool // read directories with ReadDirUnsorted
+	batch      int  // entries to read at a time, if positive
+	less       func(a, b os.FileInfo) bool
+	count      int
+	rules      []rule
+	stopped    bool
+	paused     bool
+	held       item // the current entry as of Pause
+	peeked     *peeked
+	listed     bool          // whether cur has been read ahead of Step
+	listing    []os.FileInfo // the entries of cur read ahead of Step
+	listErr    error         // the error reading them, if any
+	fatal      error         // why the walk ended early, if it did
+	stats      Stats
+	root       string
+	onError    func(path string, err error) error
+	limit      int // most entries to visit, or -1
+	every      int // entries between calls to progress
+	progress   func(count int, lastPath string)
+	ignores    map[string][]ignorePattern // by directory, until Reset
+	from       *string                    // the path set by WithFrom, if any
+	fromMark   []string                   // its elements, if beneath the root
+	fromLex    bool                       // find it by comparing paths
+	passing    bool                       // passing over entries until it
+}
+
+// peeked holds the result of the call to Step made by Peek.
+type peeked struct {
 	cur     item
-	stack   []item
 	descend bool
+	ok      bool
+	stats   Stats   // as of the peeked entry
+	errs    []error // likewise
+}
+
+// An EntryType is a simplified kind of file, as returned by
+// Walker.Type.
+type EntryType int
+
+const (
+	Unknown EntryType = iota // no info about the file
+	Regular                  // a regular file
+	Dir                      // a directory
+	Symlink                  // a symbolic link
+	Other                    // any other kind of file, such as a device
+)
+
+// Stats summarizes the entries visited by a Walker.
+type Stats struct {
+	Files    int   // regular files
+	Dirs     int   // directories
+	Symlinks int   // symbolic links
+	Bytes    int64 // total size of regular files
+	Errors   int   // entries with an error
+}
+
+func (s *Stats) add(it item) {
+	switch {
+	case it.err != nil:
+		s.Errors++
+	case it.info.Mode().IsRegular():
+		s.Files++
+		s.Bytes += it.info.Size()
+	case it.info.IsDir():
+		s.Dirs++
+	case it.info.Mode()&os.ModeSymlink != 0:
+		s.Symlinks++
+	}
+}
+
+// An Entry describes a file or directory visited by a Walker.
+type Entry struct {
+	Path  string      // as returned by Walker.Path
+	Info  os.FileInfo // as returned by Walker.Stat
+	Err   error       // as returned by Walker.Err
+	Depth int         // as returned by Walker.Depth
 }
 
 type item struct {
-	path string
-	info os.FileInfo
-	err  error
+	path   string
+	info   os.FileInfo
+	err    error
+	depth  int
+	parent *item  // directory containing this item, nil for the root
+	done   bool   // entries already pushed or not to be walked
+	hidden bool   // entries walked, but not the item itself
+	real   string // path with links resolved, once a cycle check needs it
+
+	// rest, if not nil, makes the item stand for the entries of
+	// *parent still to be read, rather than for a file.
+	rest DirIterator
+}
+
+// A rule decides whether a walk visits an item, and
+// whether it walks the item's entries.
+type rule func(w *Walker, it *item) (visit, descend bool)
+
+// elems returns the names of it and its ancestors below the root,
+// outermost first. For the root, it returns no names.
+func (it *item) elems() []string {
+	elem := make([]string, it.depth)
+	for ; it.parent != nil; it = it.parent {
+		elem[it.depth-1] = it.info.Name()
+	}
+	return elem
+}
+
+// ErrStopped is returned by Err once Step has returned
+// false because of a call to Stop.
+var ErrStopped = errors.New("fs: walk stopped")
+
+// ErrPaused is returned by Err while Step is returning false
+// because of a call to Pause.
+var ErrPaused = errors.New("fs: walk paused")
+
+// SkipDir may be returned by an error handler set with
+// SetErrorHandler, or by the function given to WalkCallback, to
+// skip a directory. It is the same value as filepath.SkipDir,
+// so either may be returned and compared against.
+var SkipDir = filepath.SkipDir
+
+// A ReadDirError is reported by Err for a directory that could not
+// be read. The directory itself is visited first, without an error.
+type ReadDirError struct {
+	Path string // the directory
+	Err  error  // as returned by the FileSystem's ReadDir
+}
+
+func (e *ReadDirError) Error() string { return e.Err.Error() }
+func (e *ReadDirError) Unwrap() error { return e.Err }
+
+// A StatError is reported by Err for a root whose info could not
+// be found.
+type StatError struct {
+	Path string // the root
+	Err  error  // as returned by the FileSystem's Lstat
+}
+
+func (e *StatError) Error() string { return e.Err.Error() }
+func (e *StatError) Unwrap() error { return e.Err }
+
+// entryErr returns the error reported for an entry without any
+// ReadDirError or StatError wrapping it.
+func entryErr(err error) error {
+	switch e := err.(type) {
+	case *ReadDirError:
+		return e.Err
+	case *StatError:
+		return e.Err
+	}
+	return err
+}
+
+// ErrCycle is reported by Err, wrapped in an *os.PathError,
+// for a directory that would be its own ancestor in the walk.
+var ErrCycle = errors.New("directory cycle")
+
+// Walk returns a new Walker rooted at root, configured by opts,
+// which are applied in order.
+func Walk(root string, opts ...Option) *Walker {
+	w := newWalker(new(fs))
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.Reset(root)
+	return w
+}
+
+// WalkMulti returns a new Walker that walks the tree at each
+// of roots in turn, in the order given. Each root has depth 0,
+// and the Path of every entry has its own root as a prefix.
+func WalkMulti(roots ...string) *Walker {
+	w := newWalker(new(fs))
+	w.reset(roots...)
+	return w
+}
+
+// WalkContext returns a new Walker rooted at root that stops
+// when ctx is done. Once ctx is done, Step returns false and
+// Err returns ctx.Err().
+func WalkContext(ctx context.Context, root string) *Walker {
+	w := Walk(root)
+	w.ctx = ctx
+	return w
+}
+
+// WalkTimeout returns a new Walker rooted at root that stops once
+// d has elapsed: Step then returns false, and Err returns
+// context.DeadlineExceeded. Like WalkContext, it checks the time
+// at the start of each Step, and needs nothing to be cleaned up.
+// Reset gives the new walk d again.
+func WalkTimeout(root string, d time.Duration) *Walker {
+	return Walk(root, WithTimeout(d))
+}
+
+// WalkFollow returns a new Walker rooted at root that follows
+// symbolic links to directories, reporting the target's info
+// through Stat and descending into it. A link to a directory
+// that is already an ancestor of the link is reported with an
+// error wrapping ErrCycle and is not descended into.
+// Other symbolic links, including dangling ones, are reported
+// as by Walk.
+func WalkFollow(root string) *Walker {
+	return Walk(root, WithFollow())
+}
+
+// WalkFollowDepth returns a new Walker rooted at root that follows
+// symbolic links to directories, as WalkFollow does, but only those
+// at depth n or less; deeper links are reported as by Walk. With n
+// of 1, for instance, links among the entries of root are followed,
+// but not links inside the directories they point to. If n is
+// negative, links are followed at any depth.
+func WalkFollowDepth(root string, n int) *Walker {
+	return Walk(root, WithFollowDepth(n))
+}
+
+// WalkErrors returns a new Walker rooted at root that records
+// every error visited by Step, for retrieval with Errors once
+// the walk is done.
+func WalkErrors(root string) *Walker {
+	w := Walk(root)
+	w.collect = true
+	return w
+}
+
+// WalkPostOrder returns a new Walker rooted at root that visits
+// the entries of each directory before the directory itself.
+// Since a directory is visited only once its entries have been,
+// SkipDir has no effect; to prune a subtree, use SetFilter, which
+// is consulted when the directory is first reached.
+func WalkPostOrder(root string) *Walker {
+	w := Walk(root)
+	w.post = true
+	return w
+}
+
+// WalkBFS returns a new Walker rooted at root that visits entries
+// breadth-first: the root, then every entry at depth 1, then every
+// entry at depth 2, and so on. Within a depth, entries are grouped
+// by directory, in the order the directories were visited, and each
+// group is in lexical order. SkipDir keeps the entries of the
+// current directory from being visited.
+func WalkBFS(root string) *Walker {
+	w := Walk(root)
+	w.bfs = true
+	return w
+}
+
+// WalkOrdered returns a new Walker rooted at root that visits the
+// subdirectories of each directory before its other entries if
+// dirsFirst is true, and after them if it is false. Each group is
+// visited in lexical order.
+func WalkOrdered(root string, dirsFirst bool) *Walker {
+	return Walk(root, WithOrdered(dirsFirst))
+}
+
+// WalkShallow returns a new Walker rooted at root that descends into
+// a directory only if Descend is called while it is being visited,
+// as if SkipDir were called for every other directory. This suits
+// expanding a tree on demand, one directory at a time, starting
+// with the root.
+func WalkShallow(root string) *Walker {
+	return Walk(root, WithShallow())
+}
+
+// WalkUnordered returns a new Walker rooted at root that reads
+// directories without sorting them, which saves time for large
+// directories when the order does not matter. The order in which
+// the entries of a directory are visited is unspecified; each
+// directory is still visited before its entries.
+func WalkUnordered(root string) *Walker {
+	return Walk(root, WithUnordered())
+}
+
+// WalkFrom returns a new Walker rooted at root that resumes a walk
+// by Walk after the entry at path after, as reported by Path: it
+// passes over every entry that Walk would visit before after, and
+// after itself, reading only the directories it needs to reach the
+// rest. The entry need not exist any more. If after is not root or
+// beneath it, every entry is visited.
+//
+// Given with WithFrom to a walk in another order, as set by
+// WithBFS, WithPostOrder or WithSortFunc, say, the Walker passes
+// over the entries it visits until it reaches after, reading every
+// directory on the way. There, after must still exist, or nothing
+// is visited.
+func WalkFrom(root, after string) *Walker {
+	return Walk(root, WithFrom(after))
+}
+
+// WalkFiles returns a new Walker rooted at root that visits every
+// entry but the directories, though it still descends into them.
+// Symbolic links and other special files are visited. Directories
+// with an error, such as one that cannot be read, are visited so
+// that the error is seen.
+func WalkFiles(root string) *Walker {
+	return Walk(root, WithFiles())
+}
+
+// WalkDirs returns a new Walker rooted at root that visits only the
+// directories, and entries with an error. Other entries are still
+// listed when their directory is read, since that is how the
+// directories in it are found, but they are passed over without
+// further cost.
+func WalkDirs(root string) *Walker {
+	return Walk(root, WithDirs())
+}
+
+// WalkSince returns a new Walker rooted at root that visits only
+// the entries modified after t, and entries with an error. The same
+// rule applies to directories, but every directory is descended
+// into, since a file can be modified without changing the
+// modification time of the directory containing it.
+func WalkSince(root string, t time.Time) *Walker {
+	return Walk(root, WithSince(t))
 }
 
-// Walk returns a new Walker rooted at root.
-func Walk(root string) *Walker {
-	return WalkFS(root, new(fs))
+// WalkN returns a new Walker rooted at root that visits at most
+// n entries. Once it has, the walk ends as if Stop had been called.
+// Entries passed over, such as those in skipped directories, do not
+// count towards n.
+func WalkN(root string, n int) *Walker {
+	w := Walk(root)
+	w.limit = n
+	return w
+}
+
+// WalkWithInfo returns a new Walker rooted at root, as by Walk,
+// that uses info, if non-nil, as the root's info instead of
+// calling Lstat. If info is nil, WalkWithInfo is the same as Walk.
+func WalkWithInfo(root string, info os.FileInfo) *Walker {
+	if info == nil {
+		return Walk(root)
+	}
+	w := newWalker(new(fs))
+	w.reset()
+	w.root = root
+	w.stack = append(w.stack, item{path: root, info: info})
+	return w
+}
+
+// WalkAbs returns a new Walker rooted at the absolute, cleaned form
+// of root, as returned by filepath.Abs, so every path it visits is
+// absolute. Reset makes its root absolute too. If filepath.Abs
+// fails, the first call to Step returns false, and Err returns the
+// error.
+func WalkAbs(root string) *Walker {
+	return Walk(root, WithAbs())
 }
 
 // WalkFS returns a new Walker rooted at root on the FileSystem fs.
+// It walks in the same order and with the same SkipDir and error
+// behavior as Walk, using only the methods of fs; in particular,
+// lexical order relies on fs.ReadDir returning sorted entries.
 func WalkFS(root string, fs FileSystem) *Walker {
-	info, err := fs.Lstat(root)
-	return &Walker{
-		fs:    fs,
-		stack: []item{{root, info, err}},
+	w := newWalker(fs)
+	w.Reset(root)
+	return w
+}
+
+// newWalker returns a Walker on fs with default settings and
+// nothing to walk.
+func newWalker(fs FileSystem) *Walker {
+	return &Walker{fs: fs, maxDepth: -1, followMax: -1, limit: -1}
+}
+
+// Reset abandons any walk in progress and starts w over at root,
+// on the same FileSystem and with the same settings, reusing w's
+// storage. The next call to Step visits root, just as for a newly
+// created Walker. Reset must not be called concurrently with Step.
+func (w *Walker) Reset(root string) {
+	w.reset(root)
+}
+
+// Expand returns a new Walker that walks again the directory at
+// path, on the same FileSystem and configured the same way as w.
+// It is meant for a directory that w was made to skip, and may be
+// called at any time, without disturbing the walk of w. The new walk
+// visits just what a walk like that of w visits at or beneath path,
+// with the same Path, Depth and RelPath, so that depth limits and
+// patterns relative to the root apply as they do for w. On the way
+// to path, it reads only the directories containing it.
+// If path is not the root of w or beneath it, the new Walker is
+// simply rooted at path.
+func (w *Walker) Expand(path string) *Walker {
+	e := *w
+	e.stack = nil // shared with w until now
+	mark, ok := w.elemsBeneath(path)
+	if !ok {
+		e.reset(path)
+		return &e
 	}
+	e.rules = append(w.rules[:len(w.rules):len(w.rules)], func(_ *Walker, it *item) (visit, descend bool) {
+		elems := it.elems()
+		for i, m := range mark {
+			if i == len(elems) {
+				return false, true // a directory containing path
+			}
+			if elems[i] != m {
+				return false, false
+			}
+		}
+		return true, true
+	})
+	e.reset(w.root)
+	return &e
+}
+
+// elemsBeneath returns the elements of path relative to the root
+// of w, and whether path is the root or beneath it.
+func (w *Walker) elemsBeneath(path string) (elems []string, ok bool) {
+	sep := string(w.fs.PathSeparator())
+	rel, err := w.fs.Rel(w.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+sep) {
+		return nil, false
+	}
+	if rel != "." {
+		elems = strings.Split(rel, sep)
+	}
+	return elems, true
+}
+
+// reset starts w over at roots, which are visited in order.
+func (w *Walker) reset(roots ...string) {
+	var (
+		bad    string
+		absErr error
+	)
+	if w.abs {
+		roots, bad, absErr = w.absRoots(roots)
+	}
+	w.root = bad
+	if len(roots) > 0 {
+		w.root = roots[0]
+	}
+	w.cur = item{}
+	w.clearStack()
+	for i := range roots {
+		if !w.bfs {
+			i = len(roots) - 1 - i
+		}
+		info, err := w.fs.Lstat(roots[i])
+		if err != nil {
+			err = &StatError{roots[i], err}
+		}
+		w.stack = append(w.stack, item{path: roots[i], info: info, err: err})
+	}
+	w.descend = false
+	w.errs = nil
+	w.count = 0
+	w.stopped = false
+	w.paused = false
+	w.held = item{}
+	w.peeked = nil
+	w.listed, w.listing, w.listErr = false, nil, nil
+	w.fatal = nil
+	w.stats = Stats{}
+	w.ignores = nil
+	if w.timed {
+		w.deadline = time.Now().Add(w.timeout)
+	}
+	w.fromMark, w.fromLex, w.passing = nil, false, false
+	if w.from != nil {
+		if mark, ok := w.elemsBeneath(*w.from); ok {
+			w.fromMark, w.fromLex = mark, w.lexical()
+			w.passing = !w.fromLex
+		}
+	}
+	if w.par != nil {
+		w.par = newParallel(w.par.workers)
+	}
+	if absErr != nil {
+		w.cur = item{path: bad, err: absErr}
+		w.fatal = absErr
+	}
+}
+
+// absRoots returns roots made absolute by the FileSystem's Abs.
+// If Abs fails, absRoots returns no roots, but the root for which
+// it failed and its error.
+func (w *Walker) absRoots(roots []string) (abs []string, bad string, err error) {
+	abs = make([]string, len(roots))
+	for i, root := range roots {
+		if abs[i], err = w.fs.Abs(root); err != nil {
+			return nil, root, err
+		}
+	}
+	return abs, "", nil
 }
 
 // Step advances the Walker to the next file or directory,
@@ -44,29 +548,398 @@ func WalkFS(root string, fs FileSystem) *Walker {
 // and Err methods.
 // It returns false when the walk stops at the end of the tree.
 func (w *Walker) Step() bool {
-	if w.descend && w.cur.err == nil && w.cur.info.IsDir() {
-		list, err := w.fs.ReadDir(w.cur.path)
-		if err != nil {
-			w.cur.err = err
-			w.stack = append(w.stack, w.cur)
-		} else {
-			for i := len(list) - 1; i >= 0; i-- {
-				path := w.fs.Join(w.cur.path, list[i].Name())
-				w.stack = append(w.stack, item{path, list[i], nil})
+	if w.paused {
+		w.cur = item{err: ErrPaused}
+		return false
+	}
+	if p := w.peeked; p != nil && !w.stopped {
+		w.peeked = nil
+		w.cur, w.descend = p.cur, p.descend
+		w.stats, w.errs = p.stats, p.errs
+		if p.ok {
+			w.tick()
+		}
+		return p.ok
+	}
+	if w.fatal != nil {
+		w.cur = item{err: w.fatal}
+		return false
+	}
+	if w.limit >= 0 && w.count >= w.limit {
+		w.Stop()
+	}
+	if w.stopped {
+		w.cur = item{err: ErrStopped}
+		w.fatal = ErrStopped
+		return false
+	}
+	if w.ctx != nil {
+		if err := w.ctx.Err(); err != nil {
+			w.cur = item{err: err}
+			w.fatal = err
+			w.clearStack()
+			return false
+		}
+	}
+	if w.timed && !time.Now().Before(w.deadline) {
+		err := context.DeadlineExceeded
+		w.cur = item{err: err}
+		w.fatal = err
+		w.clearStack()
+		return false
+	}
+
+	if w.listed {
+		if w.descend {
+			w.push(w.cur, w.listing, w.listErr)
+		}
+		w.listed, w.listing, w.listErr = false, nil, nil
+	} else if w.descend && w.canDescend(w.cur) {
+		w.expand(w.cur)
+	}
+
+	for {
+		if w.par != nil {
+			w.par.wait(w)
+		}
+		if len(w.stack) == 0 {
+			return false
+		}
+		it := w.pop()
+		if it.rest != nil {
+			w.nextBatch(it)
+			continue
+		}
+		if w.missingOK && it.parent == nil && it.info == nil && errors.Is(it.err, os.ErrNotExist) {
+			continue
+		}
+		if !it.done {
+			if w.follow && it.err == nil && it.info.Mode()&os.ModeSymlink != 0 &&
+				(w.followMax < 0 || it.depth <= w.followMax) {
+				it.info, it.err = w.followLink(it)
+			}
+			if w.cycles && it.err == nil && it.info.IsDir() && w.inCycle(it, it.info) {
+				it.err = &os.PathError{Op: "walk", Path: it.path, Err: ErrCycle}
+			}
+			visit, descend := true, true
+			if it.err == nil {
+				visit, descend = w.apply(&it)
+			}
+			if w.passing && !w.post {
+				w.passing, visit = !w.reached(it), false
+			}
+			if !visit && !descend {
+				continue
 			}
+			it.hidden = !visit
+			if (w.post || it.hidden) && descend && w.canDescend(it) {
+				it.done = w.post
+				w.expand(it)
+				continue
+			}
+			if it.hidden {
+				continue
+			}
+			if !descend {
+				// Report it, but as if SkipDir had been called.
+				it.done = true
+			}
+		}
+		if it.hidden {
+			continue
+		}
+		if w.passing {
+			// Only in a post-order walk, where entries are
+			// visited after they are first popped.
+			w.passing = !w.reached(it)
+			continue
+		}
+		if it.err != nil && w.collect {
+			w.record(it)
 		}
+		if it.err != nil && w.onError != nil {
+			switch err := w.onError(it.path, it.err); err {
+			case nil:
+				continue
+			case SkipDir:
+				if it.info == nil || !it.info.IsDir() {
+					w.skipSiblings(it)
+				}
+				continue
+			default:
+				it.err = err
+				w.cur = it
+				w.fatal = err
+				w.clearStack()
+				return false
+			}
+		}
+		w.cur = it
+		w.descend = !w.post && !it.done && !w.shallow
+		w.tick()
+		w.stats.add(it)
+		return true
 	}
+}
 
-	if len(w.stack) == 0 {
+// tick counts the entry just visited, and reports progress
+// if it is due.
+func (w *Walker) tick() {
+	w.count++
+	if w.progress != nil && w.count%w.every == 0 {
+		w.progress(w.count, w.cur.path)
+	}
+}
+
+// apply reports whether it should be visited and whether its
+// entries should be, according to the filter and rules of w.
+func (w *Walker) apply(it *item) (visit, descend bool) {
+	if w.filter != nil && !w.filter(it.path, it.info) {
+		return false, false
+	}
+	if w.skipHidden && it.parent != nil && strings.HasPrefix(w.fs.Base(it.path), ".") {
+		return false, false
+	}
+	visit, descend = it.depth >= w.minDepth, true
+	if w.fromLex {
+		visit, descend = w.fromOrder(it)
+		visit = visit && it.depth >= w.minDepth
+	}
+	for _, r := range w.rules {
+		v, d := r(w, it)
+		visit, descend = visit && v, descend && d
+	}
+	return visit, descend
+}
+
+// expand reads the entries of directory it and pushes them,
+// or in a parallel walk, queues it to be read.
+func (w *Walker) expand(it item) {
+	if w.par != nil {
+		w.par.queue = append(w.par.queue, it)
+		return
+	}
+	if w.batched() {
+		rest, err := w.fs.(BatchFileSystem).ReadDirBatch(it.path, w.batch)
+		if err != nil {
+			w.push(it, nil, err)
+			return
+		}
+		parent := new(item)
+		*parent = it
+		w.nextBatch(item{parent: parent, rest: rest})
+		return
+	}
+	list, err := w.readDir(it.path)
+	w.push(it, list, err)
+}
+
+// batched reports whether w reads directories in batches.
+func (w *Walker) batched() bool {
+	_, ok := w.fs.(BatchFileSystem)
+	return ok && w.batch > 0 && w.less == nil && !w.bfs && !w.post
+}
+
+// lexical reports whether w visits entries in the order Walk does:
+// depth first, each directory before its entries, and the entries
+// of each directory sorted by name.
+func (w *Walker) lexical() bool {
+	return w.less == nil && !w.bfs && !w.post && !w.unordered && w.par == nil && !w.batched()
+}
+
+// fromOrder reports whether it comes after the path set by
+// WithFrom in the order Walk visits entries, and whether its
+// entries might.
+func (w *Walker) fromOrder(it *item) (visit, descend bool) {
+	for i, e := range it.elems() {
+		if i == len(w.fromMark) {
+			return true, true // beneath the path
+		}
+		if e != w.fromMark[i] {
+			return e > w.fromMark[i], e > w.fromMark[i]
+		}
+	}
+	// It is the path, or a directory containing it.
+	return false, true
+}
+
+// reached reports whether it is at the path set by WithFrom.
+func (w *Walker) reached(it item) bool {
+	elems := it.elems()
+	if len(elems) != len(w.fromMark) {
 		return false
 	}
-	i := len(w.stack) - 1
-	w.cur = w.stack[i]
-	w.stack = w.stack[:i]
-	w.descend = true
+	for i, e := range elems {
+		if e != w.fromMark[i] {
+			return false
+		}
+	}
 	return true
 }
 
+// nextBatch reads the next entries of the directory that r stands
+// for and pushes them, with r beneath them to read the rest.
+func (w *Walker) nextBatch(r item) {
+	list, err := r.rest.Next()
+	if err == nil {
+		w.stack = append(w.stack, r)
+	} else {
+		r.rest.Close()
+	}
+	w.pushList(r.parent, list)
+	if err != nil && err != io.EOF {
+		w.push(*r.parent, nil, err)
+	}
+}
+
+// clearStack empties the stack, closing any directories
+// still being read.
+func (w *Walker) clearStack() {
+	for i, it := range w.stack {
+		if it.rest != nil {
+			it.rest.Close()
+		}
+		w.stack[i] = item{}
+	}
+	w.stack = w.stack[:0]
+}
+
+// sort sorts list with the function set by SetSortFunc, if any.
+func (w *Walker) sort(list []os.FileInfo) {
+	if w.less != nil {
+		sort.SliceStable(list, func(i, j int) bool { return w.less(list[i], list[j]) })
+	}
+}
+
+// readDir reads the named directory, sorted unless w is unordered.
+func (w *Walker) readDir(dirname string) ([]os.FileInfo, error) {
+	if w.unordered {
+		return w.fs.ReadDirUnsorted(dirname)
+	}
+	return w.fs.ReadDir(dirname)
+}
+
+// pop removes the next item to visit from the stack and returns it.
+func (w *Walker) pop() item {
+	var it item
+	if w.bfs {
+		it, w.stack[0] = w.stack[0], item{}
+		w.stack = w.stack[1:]
+	} else {
+		i := len(w.stack) - 1
+		it = w.stack[i]
+		w.stack = w.stack[:i]
+	}
+	return it
+}
+
+// canDescend reports whether w should read the entries of it.
+func (w *Walker) canDescend(it item) bool {
+	return it.err == nil && it.info.IsDir() &&
+		(w.maxDepth < 0 || it.depth < w.maxDepth) &&
+		(w.dirFilter == nil || w.dirFilter(it.path, it.info))
+}
+
+// push adds the entries of directory dir, as read by ReadDir,
+// to the stack, so that they are visited in order. If ReadDir
+// failed, dir itself is pushed again with the error, to be
+// reported even if it was hidden. In a post-order walk, dir is
+// pushed beneath its entries.
+func (w *Walker) push(dir item, list []os.FileInfo, err error) {
+	if err != nil {
+		dir.err, dir.hidden = &ReadDirError{dir.path, err}, false
+		if w.bfs {
+			// Report the error next, not after the rest of the queue.
+			w.stack = append([]item{dir}, w.stack...)
+		} else {
+			w.stack = append(w.stack, dir)
+		}
+		return
+	}
+	if dir.done {
+		w.stack = append(w.stack, dir)
+	}
+	w.sort(list)
+	parent := new(item)
+	*parent = dir
+	w.pushList(parent, list)
+}
+
+// pushList adds list, the entries of directory parent,
+// to the stack.
+func (w *Walker) pushList(parent *item, list []os.FileInfo) {
+	for i := range list {
+		if !w.bfs {
+			i = len(list) - 1 - i
+		}
+		w.stack = append(w.stack, item{
+			path:   w.fs.Join(parent.path, list[i].Name()),
+			info:   list[i],
+			depth:  parent.depth + 1,
+			parent: parent,
+		})
+	}
+}
+
+// record appends the error of it to w.errs, making sure
+// that it carries the path.
+func (w *Walker) record(it item) {
+	err := it.err
+	switch err.(type) {
+	case *os.PathError, *ReadDirError, *StatError:
+	default:
+		err = &os.PathError{Op: "walk", Path: it.path, Err: err}
+	}
+	w.errs = append(w.errs, err)
+}
+
+// followLink returns the info of the directory that the symbolic
+// link it points to. If it does not point to a directory, the
+// link's own info is returned unchanged.
+func (w *Walker) followLink(it item) (os.FileInfo, error) {
+	info, err := w.fs.Stat(it.path)
+	if err != nil || !info.IsDir() {
+		return it.info, nil
+	}
+	if w.inCycle(it, info) {
+		return it.info, &os.PathError{Op: "walk", Path: it.path, Err: ErrCycle}
+	}
+	return info, nil
+}
+
+// inCycle reports whether the directory described by info, found
+// at the path of it, is the same as one of the ancestors of it.
+// FileInfos from package os are compared with os.SameFile. Others,
+// which os.SameFile cannot tell apart, are compared by their paths
+// with symbolic links resolved.
+func (w *Walker) inCycle(it item, info os.FileInfo) bool {
+	if os.SameFile(info, info) {
+		for p := it.parent; p != nil; p = p.parent {
+			if os.SameFile(p.info, info) {
+				return true
+			}
+		}
+		return false
+	}
+	target, err := resolveLinks(w.fs, it.path, true)
+	if err != nil {
+		return false
+	}
+	for p := it.parent; p != nil; p = p.parent {
+		if p.real == "" {
+			resolved, err := resolveLinks(w.fs, p.path, true)
+			if err != nil {
+				continue
+			}
+			p.real = resolved
+		}
+		if p.real == target {
+			return true
+		}
+	}
+	return false
+}
+
 // Path returns the path to the most recent file or directory
 // visited by a call to Step. It contains the argument to Walk
 // as a prefix; that is, if Walk is called with "dir", which is
@@ -75,21 +948,562 @@ func (w *Walker) Path() string {
 	return w.cur.path
 }
 
+// Root returns the root of the walk, exactly as it was given
+// to Walk or Reset, unless w was made by WalkAbs or with WithAbs,
+// in which case it returns the absolute form that w walks. For a
+// Walker made by WalkMulti, it returns the first of the roots.
+func (w *Walker) Root() string {
+	return w.root
+}
+
+// RelPath returns Path relative to the root of the walk,
+// joined with the FileSystem's Join. For the root itself it
+// returns ".".
+func (w *Walker) RelPath() string {
+	if w.cur.parent == nil {
+		return "."
+	}
+	return w.fs.Join(w.cur.elems()...)
+}
+
+// ToSlash returns Path with each of the FileSystem's separators
+// replaced by a slash, as filepath.ToSlash does for the OS. The
+// result is the same on every system, as for a key in an archive.
+func (w *Walker) ToSlash() string {
+	sep := w.fs.PathSeparator()
+	if sep == '/' {
+		return w.cur.path
+	}
+	return strings.ReplaceAll(w.cur.path, string(sep), "/")
+}
+
+// Name returns the last element of Path, as the FileSystem's
+// Base would. Trailing separators are removed before the last
+// element is taken, so a root of "dir/" has name "dir".
+func (w *Walker) Name() string {
+	return w.fs.Base(w.cur.path)
+}
+
 // Stat returns info for the most recent file or directory
 // visited by a call to Step.
 func (w *Walker) Stat() os.FileInfo {
 	return w.cur.info
 }
 
+// IsDir reports whether the most recent file or directory
+// visited by a call to Step is a directory. It returns false
+// if there is no info for the entry, as when Err is non-nil.
+func (w *Walker) IsDir() bool {
+	return w.cur.info != nil && w.cur.info.IsDir()
+}
+
+// ModTime returns the modification time of the most recent file
+// or directory visited by a call to Step. It returns the zero Time
+// if there is no info for the entry, as when Err is non-nil.
+func (w *Walker) ModTime() time.Time {
+	if w.cur.info == nil {
+		return time.Time{}
+	}
+	return w.cur.info.ModTime()
+}
+
+// Size returns the size in bytes of the most recent file or
+// directory visited by a call to Step, as reported by its info;
+// for anything other than a regular file, the meaning of the size
+// depends on the system. It returns 0 if there is no info for the
+// entry, as when Err is non-nil.
+func (w *Walker) Size() int64 {
+	if w.cur.info == nil {
+		return 0
+	}
+	return w.cur.info.Size()
+}
+
+// Type returns the kind of the most recent file or directory
+// visited by a call to Step. It returns Unknown if there is no
+// info for the entry, as when Err is non-nil.
+func (w *Walker) Type() EntryType {
+	if w.cur.info == nil {
+		return Unknown
+	}
+	switch m := w.cur.info.Mode(); {
+	case m.IsRegular():
+		return Regular
+	case m.IsDir():
+		return Dir
+	case m&os.ModeSymlink != 0:
+		return Symlink
+	}
+	return Other
+}
+
+// LinkTarget returns the destination of the most recent symbolic
+// link visited by a call to Step, as returned by the FileSystem's
+// Readlink. It does not affect whether w walks the destination.
+// If the entry is not a symbolic link, LinkTarget returns an error.
+func (w *Walker) LinkTarget() (string, error) {
+	return w.fs.Readlink(w.cur.path)
+}
+
+// StatTarget returns info for the most recent file or directory
+// visited by a call to Step, following it if it is a symbolic link,
+// through the FileSystem's Stat. For anything else, it returns what
+// Stat does. It does not affect whether w walks the destination. If
+// there is no info for the entry, StatTarget returns Err.
+func (w *Walker) StatTarget() (os.FileInfo, error) {
+	if w.cur.info == nil {
+		return nil, w.cur.err
+	}
+	if w.cur.info.Mode()&os.ModeSymlink == 0 {
+		return w.cur.info, nil
+	}
+	return w.fs.Stat(w.cur.path)
+}
+
+// Depth returns the depth of the most recent file or directory
+// visited by a call to Step, relative to the root of the walk.
+// The root itself has depth 0, its entries depth 1, and so on.
+func (w *Walker) Depth() int {
+	return w.cur.depth
+}
+
+// IsSingleFile reports whether w is on a root that is not a
+// directory, so that the walk from that root visits nothing else.
+// SkipDir has no effect there. IsSingleFile returns false for a
+// root with an error.
+func (w *Walker) IsSingleFile() bool {
+	return w.cur.parent == nil && w.cur.err == nil && w.cur.info != nil && !w.cur.info.IsDir()
+}
+
+// Count returns the number of entries visited so far,
+// including the root. After the walk is done, it is the total.
+func (w *Walker) Count() int {
+	return w.count
+}
+
+// Entry returns the most recent file or directory visited by a
+// call to Step. Unlike the other accessors, the result does not
+// change with later calls to Step, and can be kept or handed to
+// another goroutine while the walk goes on.
+func (w *Walker) Entry() Entry {
+	return Entry{w.cur.path, w.cur.info, w.cur.err, w.cur.depth}
+}
+
+// Next advances w to the next file or directory, as Step does, and
+// returns it as Entry would. If the walk is over, Next returns nil
+// and false. Each call returns a new Entry, which the caller may
+// keep.
+//
+//	for e, ok := w.Next(); ok; e, ok = w.Next() {
+//		...
+//	}
+func (w *Walker) Next() (*Entry, bool) {
+	if !w.Step() {
+		return nil, false
+	}
+	e := w.Entry()
+	return &e, true
+}
+
+// DirEntry returns the most recent file or directory visited by
+// a call to Step as an io/fs.DirEntry, for code written for
+// fs.WalkDir, or nil if there is no info for the entry, as when
+// Err is non-nil. Its Info method returns what Stat does.
+//
+// Unlike fs.WalkDir, Walker does not defer finding the info of
+// an entry until Info is called: it needs the info of every entry
+// it visits, if only for Stats, so it reads directories with the
+// ReadDir of its FileSystem, which for the one used by Walk calls
+// Lstat for each entry. Info adds no call of its own.
+func (w *Walker) DirEntry() iofs.DirEntry {
+	if w.cur.info == nil {
+		return nil
+	}
+	return dirEntry{w.cur.info}
+}
+
+// NextDir advances w, as Step does, to the next directory whose
+// entries are to be walked, and returns its path and its entries,
+// in the order they will be visited. Entries up to that directory
+// are passed over, as are directories with an error. If the
+// directory cannot be read, entries is nil and Err returns the
+// error. Calling SkipDir before the next call to Step or NextDir
+// keeps the entries from being walked. NextDir returns false when
+// no directories are left; in a post-order walk, it always does.
+func (w *Walker) NextDir() (dir string, entries []os.FileInfo, ok bool) {
+	for w.Step() {
+		if !w.list() {
+			continue
+		}
+		if err := w.listErr; err != nil {
+			w.listed, w.listing, w.listErr = false, nil, nil
+			w.cur.err = &ReadDirError{w.cur.path, err}
+			if w.collect {
+				w.record(w.cur)
+			}
+			return w.cur.path, nil, true
+		}
+		return w.cur.path, w.listing, true
+	}
+	return "", nil, false
+}
+
+// ChildCount returns the number of entries in the directory most
+// recently visited by a call to Step, reading it then rather than
+// at the next call to Step, which visits the entries read. It
+// returns -1 if w is not on a directory whose entries are to be
+// walked, as after SkipDir, or in a post-order walk, and also if
+// the directory cannot be read; the next call to Step then reports
+// the error, as usual.
+func (w *Walker) ChildCount() int {
+	if !w.list() || w.listErr != nil {
+		return -1
+	}
+	return len(w.listing)
+}
+
+// list reads the entries of the current directory for the next
+// call to Step, if they are to be walked and have not been read
+// already, and reports whether they have been.
+func (w *Walker) list() bool {
+	if w.listed {
+		return w.descend
+	}
+	if !w.descend || !w.canDescend(w.cur) {
+		return false
+	}
+	list, err := w.readDir(w.cur.path)
+	w.sort(list)
+	w.listed, w.listing, w.listErr = true, list, err
+	return true
+}
+
+// Stats returns a summary of the entries visited so far.
+// Entries with an error count only as errors.
+func (w *Walker) Stats() Stats {
+	return w.stats
+}
+
 // Err returns the error, if any, for the most recent attempt
 // by Step to visit a file or directory. If a directory has
 // an error, w will not descend into that directory.
+// An error reading a directory is a *ReadDirError, and one finding
+// the info of a root is a *StatError. Both wrap the error from the
+// FileSystem, which errors.Is sees through but os.IsNotExist and
+// the like do not: use errors.Is(err, os.ErrNotExist) instead.
 func (w *Walker) Err() error {
 	return w.cur.err
 }
 
+// WalkErr returns the error, if any, that ended the walk early,
+// once Step has returned false. It is nil if the walk reached the
+// end of the tree. Unlike Err, it does not report errors for single
+// entries, such as a root that does not exist, which Step visits
+// like any other entry; it reports ErrStopped after Stop, the
+// context's error for a canceled walk, and any error returned by
+// the function set by SetErrorHandler.
+//
+//	for w.Step() {
+//		...
+//	}
+//	if err := w.WalkErr(); err != nil {
+//		...
+//	}
+func (w *Walker) WalkErr() error {
+	return w.fatal
+}
+
+// SetMaxDepth limits the walk to entries at most n levels below
+// the root. Directories at depth n are still visited, but w does
+// not descend into them, as if SkipDir had been called. A depth of
+// 0 visits only the root; a negative depth removes the limit.
+// SetMaxDepth must be called before the first call to Step.
+func (w *Walker) SetMaxDepth(n int) {
+	w.maxDepth = n
+}
+
+// SetMinDepth causes w to pass over entries less than n levels
+// below the root, though it still descends into directories among
+// them to reach deeper entries. The root has depth 0.
+// SetMinDepth must be called before the first call to Step.
+func (w *Walker) SetMinDepth(n int) {
+	w.minDepth = n
+}
+
+// SetDirFilter sets a function that decides which directories
+// have their entries read. It is called just before w would read
+// a directory, and not for directories passed over by SkipDir or
+// beyond the maximum depth. If f returns false, the directory is
+// still visited, but as if SkipDir had been called.
+// SetDirFilter must be called before the first call to Step.
+func (w *Walker) SetDirFilter(f func(path string, info os.FileInfo) bool) {
+	w.dirFilter = f
+}
+
+// DetectCycles sets whether w checks each directory it reaches
+// against the directories containing it, as WalkFollow does for
+// symbolic links, so that bind mounts and the like cannot make it
+// loop forever. A directory that is the same as one of its
+// ancestors is reported with an error wrapping ErrCycle and is not
+// descended into. Directories are compared with os.SameFile if their
+// FileInfos come from package os, and otherwise by their paths with
+// symbolic links resolved through the FileSystem. DetectCycles
+// should be called before the first call to Step.
+func (w *Walker) DetectCycles(enable bool) {
+	w.cycles = enable
+}
+
+// IgnoreMissingRoot sets whether w passes over a root that does
+// not exist, instead of visiting it with an error, so that walking
+// a missing directory is the same as walking an empty one. Roots
+// with any other error are still visited. IgnoreMissingRoot should
+// be called before the first call to Step.
+func (w *Walker) IgnoreMissingRoot(enable bool) {
+	w.missingOK = enable
+}
+
+// SkipHidden sets whether w passes over hidden entries, those
+// whose name begins with ".", along with everything beneath them.
+// The root is visited even if its name begins with ".".
+// SkipHidden should be called before the first call to Step.
+func (w *Walker) SkipHidden(enable bool) {
+	w.skipHidden = enable
+}
+
+// SetFilter sets a function that decides which entries are
+// visited, starting with the root. If f returns false for an
+// entry, Step passes over it, and if it is a directory, over
+// everything in it. Entries with an error are always visited,
+// without consulting f.
+// SetFilter must be called before the first call to Step.
+func (w *Walker) SetFilter(f func(path string, info os.FileInfo) bool) {
+	w.filter = f
+}
+
+// Errors returns the errors visited so far, in the order
+// they were visited, by a Walker created with WalkErrors.
+// Each error names the failed entry: it is a *ReadDirError or a
+// *StatError, as Err reports, or else an *os.PathError.
+// For other Walkers, it returns nil.
+func (w *Walker) Errors() []error {
+	return w.errs
+}
+
+// SetSortFunc sets the order in which the entries of each
+// directory are visited: a before b if less(a, b). Entries that
+// less considers equal keep their lexical order. If less is nil,
+// as it is by default, entries are visited in lexical order.
+// SetSortFunc must be called before the first call to Step.
+func (w *Walker) SetSortFunc(less func(a, b os.FileInfo) bool) {
+	w.less = less
+}
+
+// SetReadDirBatch makes w read directories n entries at a time, if
+// its FileSystem is a BatchFileSystem, as the one used by Walk is.
+// A directory is then never held in memory whole, however large it
+// is, but its entries cannot be sorted: they are visited in the
+// order the FileSystem returns them. A directory also stays open
+// until all its entries have been read, so one directory for each
+// level of the tree may be open at once. Post-order, breadth-first,
+// and parallel walks, and walks with a sort function, read whole
+// directories regardless, and keep their order. If n is less than
+// 1, w reads whole directories, as by default.
+// SetReadDirBatch must be called before the first call to Step.
+func (w *Walker) SetReadDirBatch(n int) {
+	w.batch = n
+}
+
+// Peek returns the entry that the next call to Step will visit,
+// without advancing w; ok is false if Step will return false.
+// Calling Peek and then Step is the way to look ahead in a walk.
+// Since Peek must decide whether to descend into the current
+// entry, SkipDir has no effect between Peek and the next Step.
+// While w is paused, Peek returns ErrPaused, as Step would, and
+// looks no further ahead.
+func (w *Walker) Peek() (path string, info os.FileInfo, err error, ok bool) {
+	if w.paused {
+		return "", nil, ErrPaused, false
+	}
+	if w.peeked == nil {
+		cur, count, progress, stats, errs := w.cur, w.count, w.progress, w.stats, w.errs
+		w.progress = nil // the entry is reported when Step reaches it
+		ok := w.Step()
+		w.peeked = &peeked{w.cur, w.descend, ok, w.stats, w.errs}
+		w.cur, w.count, w.descend, w.progress = cur, count, false, progress
+		w.stats, w.errs = stats, errs
+	}
+	p := w.peeked
+	return p.cur.path, p.cur.info, p.cur.err, p.ok
+}
+
+// Stop ends the walk: later calls to Step return false,
+// and Err then returns ErrStopped. Stop may be called at any
+// time, including before the first call to Step.
+func (w *Walker) Stop() {
+	w.stopped = true
+	w.clearStack()
+	if w.par != nil {
+		w.par.queue = nil
+	}
+}
+
+// Pause suspends the walk without losing its place: until Resume
+// is called, Step returns false, and Err then returns ErrPaused.
+// Pause does not block, nor does it wait for anything; like the
+// other methods of w, it must be called by the goroutine using w.
+func (w *Walker) Pause() {
+	if !w.paused {
+		w.paused = true
+		w.held = w.cur
+	}
+}
+
+// Resume continues a walk suspended by Pause. The accessors once
+// again describe the entry visited before Pause, and the next call
+// to Step carries on from there, descending into it if it is a
+// directory and SkipDir has not been called.
+func (w *Walker) Resume() {
+	if w.paused {
+		w.paused = false
+		w.cur = w.held
+		w.held = item{}
+	}
+}
+
+// Close stops the walk, as Stop does, and for a parallel walk
+// waits for any directory reads in progress to finish, so that
+// w holds on to no further resources. Close may be called more
+// than once, and after the walk is done. It always returns nil.
+func (w *Walker) Close() error {
+	w.Stop()
+	if w.par != nil {
+		w.par.drain()
+	}
+	return nil
+}
+
+// Stopped reports whether the walk was ended by Stop,
+// rather than by reaching the end of the tree.
+func (w *Walker) Stopped() bool {
+	return w.stopped
+}
+
+// SetErrorHandler sets a function to be called with the path and
+// error of each entry that has an error, in place of visiting the
+// entry. If h returns nil, Step passes over the entry and goes on.
+// If h returns SkipDir, Step passes over the entry and, as in
+// filepath.Walk, its entries if it is a directory, or the rest of
+// the directory containing it otherwise. Since a directory with an
+// error is not descended into anyway, SkipDir is then the same as
+// nil. Any other error ends the walk: Step returns false, and Err
+// returns the error.
+// SetErrorHandler must be called before the first call to Step.
+func (w *Walker) SetErrorHandler(h func(path string, err error) error) {
+	w.onError = h
+}
+
+// SetProgress sets a function to be called by Step each time the
+// number of entries visited, as Count returns it, reaches a multiple
+// of every. It is passed that number and the Path of the entry. Since
+// fn is called from within Step, it must not itself call methods
+// of w. If every is less than 1 or fn is nil, SetProgress turns
+// progress reports off.
+func (w *Walker) SetProgress(every int, fn func(count int, lastPath string)) {
+	if every < 1 {
+		fn = nil
+	}
+	w.every, w.progress = every, fn
+}
+
 // SkipDir causes the currently visited directory to be skipped.
 // If w is not on a directory, SkipDir has no effect.
+// On the root, it leaves nothing more to walk: the next call to
+// Step returns false, and Err and WalkErr return nil. For a Walker
+// made by WalkMulti, the walk goes on with the next root.
 func (w *Walker) SkipDir() {
 	w.descend = false
 }
+
+// SkipSiblings causes w to pass over the remaining entries of the
+// directory containing the current entry, without descending into
+// any of them, and to go on with the rest of the walk. The current
+// entry itself is still descended into unless SkipDir is also called.
+// On the root, SkipSiblings has no effect. Like SkipDir, it also has
+// no effect after Peek.
+func (w *Walker) SkipSiblings() {
+	if w.peeked == nil {
+		w.skipSiblings(w.cur)
+	}
+}
+
+// skipSiblings removes the remaining entries of the directory
+// containing it from the stack.
+func (w *Walker) skipSiblings(it item) {
+	if it.parent == nil {
+		return
+	}
+	kept := w.stack[:0]
+	for _, s := range w.stack {
+		if s.parent != it.parent {
+			kept = append(kept, s)
+		} else if s.rest != nil {
+			s.rest.Close()
+		}
+	}
+	for i := len(kept); i < len(w.stack); i++ {
+		w.stack[i] = item{}
+	}
+	w.stack = kept
+}
+
+// Descend causes w to descend into the currently visited directory,
+// undoing any call to SkipDir. It is needed only for a Walker made
+// by WalkShallow, which otherwise descends into no directories.
+// If w is not on a directory, or the directory's entries are not to
+// be walked, as in a post-order walk or because of a filter, Descend
+// has no effect. Like SkipDir, it also has no effect after Peek.
+func (w *Walker) Descend() {
+	w.descend = !w.post && !w.cur.done
+}
+
+// Skip causes w not to descend into the current entry, whatever
+// its type. On a directory it is the same as SkipDir; on any
+// other entry there is nothing to descend into, and it has no
+// effect.
+func (w *Walker) Skip() {
+	w.SkipDir()
+}
+
+// Collect runs the walk of w to the end and returns every entry
+// it visits. Errors for single entries are recorded in their Err
+// fields; the error result is non-nil only if the walk ended early,
+// as when its context is done.
+func Collect(w *Walker) ([]Entry, error) {
+	var entries []Entry
+	for w.Step() {
+		entries = append(entries, w.Entry())
+	}
+	return entries, w.WalkErr()
+}
+
+// WalkChan walks the tree rooted at root, as WalkContext does, on
+// a new goroutine, and sends each entry it visits on the returned
+// channel. Entries with an error are sent like any other, and the
+// walk goes on. The channel is closed once the walk is over or ctx
+// is done. The caller must receive every entry or cancel ctx;
+// otherwise the goroutine is never released.
+func WalkChan(ctx context.Context, root string) <-chan Entry {
+	c := make(chan Entry)
+	go func() {
+		defer close(c)
+		w := WalkContext(ctx, root)
+		for w.Step() {
+			select {
+			case c <- w.Entry():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
@@ -5,10 +5,22 @@
 package fs_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kr/fs"
 )
@@ -207,3 +219,2068 @@ func TestBug3486(t *testing.T) { // http://code.google.com/p/go/issues/detail?id
 		t.Fatalf("%q not seen", src)
 	}
 }
+
+var (
+	mapDir  = &fs.MapFile{Mode: os.ModeDir | 0755}
+	mapFile = &fs.MapFile{Mode: 0644}
+)
+
+// errFS is a MapFS on which ReadDir fails for the directories in bad.
+type errFS struct {
+	fs.MapFS
+	bad map[string]bool
+}
+
+func (e errFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	if e.bad[dirname] {
+		return nil, &os.PathError{Op: "readdir", Path: dirname, Err: os.ErrPermission}
+	}
+	return e.MapFS.ReadDir(dirname)
+}
+
+func TestWalkFS(t *testing.T) {
+	m := errFS{
+		MapFS: fs.MapFS{
+			"r":     mapDir,
+			"r/c":   mapFile,
+			"r/a":   mapFile,
+			"r/b":   mapDir,
+			"r/b/x": mapFile,
+			"r/d":   mapDir,
+			"r/d/y": mapFile,
+			"r/e":   mapDir,
+			"r/e/z": mapFile,
+		},
+		bad: map[string]bool{"r/d": true},
+	}
+	var got []string
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			got = append(got, "error "+walker.Path())
+			continue
+		}
+		if walker.IsDir() != m.MapFS[walker.Path()].Mode.IsDir() {
+			t.Errorf("IsDir() at %s = %v", walker.Path(), walker.IsDir())
+		}
+		got = append(got, walker.Path())
+		if walker.Path() == "r/e" {
+			walker.SkipDir()
+		}
+	}
+	want := []string{"r", "r/a", "r/b", "r/b/x", "r/c", "r/d", "error r/d", "r/e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	walker = fs.WalkFS("missing", m)
+	if !walker.Step() || walker.Err() == nil {
+		t.Fatalf("expected error for missing root")
+	}
+	if walker.IsDir() {
+		t.Errorf("IsDir() = true for missing root")
+	}
+}
+
+// backslashFS is a MapFS whose paths are separated by backslashes.
+type backslashFS struct {
+	fs.MapFS
+}
+
+func (b backslashFS) slash(p string) string { return strings.ReplaceAll(p, `\`, "/") }
+
+func (b backslashFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return b.MapFS.ReadDir(b.slash(dirname))
+}
+
+func (b backslashFS) Lstat(name string) (os.FileInfo, error) { return b.MapFS.Lstat(b.slash(name)) }
+
+func (b backslashFS) Join(elem ...string) string {
+	return strings.ReplaceAll(b.MapFS.Join(elem...), "/", `\`)
+}
+
+func (b backslashFS) PathSeparator() byte { return '\\' }
+
+func TestWalkToSlash(t *testing.T) {
+	m := backslashFS{fs.MapFS{"r/a": mapFile, "r/b/c": mapFile}}
+	var got []string
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, walker.Path()+" "+walker.RelPath()+" "+walker.ToSlash())
+	}
+	want := []string{
+		`r . r`,
+		`r\a a r/a`,
+		`r\b b r/b`,
+		`r\b\c b\c r/b/c`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkDepth(t *testing.T) {
+	m := fs.MapFS{
+		"r":       mapDir,
+		"r/a":     mapFile,
+		"r/b":     mapDir,
+		"r/b/c":   mapDir,
+		"r/b/c/d": mapFile,
+	}
+	want := map[string]int{"r": 0, "r/a": 1, "r/b": 1, "r/b/c": 2, "r/b/c/d": 3}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		if d := walker.Depth(); d != want[walker.Path()] {
+			t.Errorf("Depth() at %s = %d, want %d", walker.Path(), d, want[walker.Path()])
+		}
+	}
+}
+
+func TestWalkRelPath(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	for _, root := range []string{tree.name, tree.name + "//", "./" + tree.name} {
+		walker := fs.Walk(root)
+		for walker.Step() {
+			want, err := filepath.Rel(root, walker.Path())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := walker.RelPath(); got != want {
+				t.Errorf("RelPath() at %s = %q, want %q", walker.Path(), got, want)
+			}
+		}
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	m := fs.MapFS{
+		"r":       mapDir,
+		"r/a":     mapFile,
+		"r/b":     mapDir,
+		"r/b/c":   mapDir,
+		"r/b/c/d": mapFile,
+	}
+	tests := []struct {
+		max  int
+		want []string
+	}{
+		{0, []string{"r"}},
+		{1, []string{"r", "r/a", "r/b"}},
+		{2, []string{"r", "r/a", "r/b", "r/b/c"}},
+		{-1, []string{"r", "r/a", "r/b", "r/b/c", "r/b/c/d"}},
+	}
+	for _, test := range tests {
+		var got []string
+		walker := fs.WalkFS("r", m)
+		walker.SetMaxDepth(test.max)
+		for walker.Step() {
+			got = append(got, walker.Path())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("max depth %d: walk = %q, want %q", test.max, got, test.want)
+		}
+	}
+}
+
+func TestWalkPeek(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":   mapFile,
+		"r/b/c": mapFile,
+		"r/d":   mapFile,
+	}
+	var got []string
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		path := walker.Path()
+		next, _, _, ok := walker.Peek()
+		if again, _, _, _ := walker.Peek(); again != next {
+			t.Errorf("second Peek() = %q, want %q", again, next)
+		}
+		if walker.Path() != path {
+			t.Errorf("Path() after Peek() = %q, want %q", walker.Path(), path)
+		}
+		if !ok {
+			next = "end"
+		}
+		got = append(got, path+" "+next)
+	}
+	want := []string{"r r/a", "r/a r/b", "r/b r/b/c", "r/b/c r/d", "r/d end"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+	if n := walker.Count(); n != 5 {
+		t.Errorf("Count() = %d, want 5", n)
+	}
+
+	// Stats and Errors count the peeked entry only once it is visited.
+	walker = fs.WalkFS("r", m)
+	walker.Step()
+	walker.Peek()
+	if n := walker.Stats().Files; n != 0 {
+		t.Errorf("Stats().Files after Peek() = %d, want 0", n)
+	}
+	if walker.Step(); walker.Stats().Files != 1 {
+		t.Errorf("Stats().Files after Step() = %d, want 1", walker.Stats().Files)
+	}
+	walker = fs.Walk("missing", fs.WithFileSystem(m), fs.WithErrors())
+	walker.Peek()
+	if errs := walker.Errors(); len(errs) != 0 {
+		t.Errorf("Errors() after Peek() = %v, want none", errs)
+	}
+	if walker.Step(); len(walker.Errors()) != 1 {
+		t.Errorf("Errors() after Step() = %v, want one error", walker.Errors())
+	}
+
+	// A limit reached by Peek is reported by the next Step.
+	walker = fs.Walk("r", fs.WithFileSystem(m), fs.WithLimit(1))
+	walker.Step()
+	if _, _, err, ok := walker.Peek(); ok || err != fs.ErrStopped {
+		t.Errorf("Peek() at limit = %v, %v, want %v, false", err, ok, fs.ErrStopped)
+	}
+	if walker.Step() {
+		t.Errorf("Step() after Peek() at limit = true, want false")
+	}
+	if err := walker.Err(); err != fs.ErrStopped {
+		t.Errorf("Err() after Peek() at limit = %v, want %v", err, fs.ErrStopped)
+	}
+}
+
+func TestWalkType(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":   mapFile,
+		"r/b":   mapDir,
+		"r/c":   {Data: []byte("a"), Mode: os.ModeSymlink},
+		"r/d":   {Mode: os.ModeDevice},
+		"r/e/f": mapFile,
+	}
+	want := map[string]fs.EntryType{
+		"r":     fs.Dir,
+		"r/a":   fs.Regular,
+		"r/b":   fs.Dir,
+		"r/c":   fs.Symlink,
+		"r/d":   fs.Other,
+		"r/e":   fs.Dir,
+		"r/e/f": fs.Regular,
+	}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		if typ := walker.Type(); typ != want[walker.Path()] {
+			t.Errorf("Type() at %s = %v, want %v", walker.Path(), typ, want[walker.Path()])
+		}
+	}
+	walker = fs.WalkFS("missing", m)
+	walker.Step()
+	if typ := walker.Type(); typ != fs.Unknown {
+		t.Errorf("Type() for missing root = %v, want %v", typ, fs.Unknown)
+	}
+}
+
+func TestWalkDirEntry(t *testing.T) {
+	m := fs.MapFS{"r/a": {Data: []byte("hello")}, "r/b/c": mapFile}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		d := walker.DirEntry()
+		info, err := d.Info()
+		if d.Name() != walker.Name() || d.IsDir() != walker.IsDir() ||
+			d.Type() != walker.Stat().Mode().Type() || info != walker.Stat() || err != nil {
+			t.Errorf("DirEntry() at %s = %v, doesn't match %v", walker.Path(), d, walker.Stat())
+		}
+	}
+	walker = fs.WalkFS("missing", m)
+	walker.Step()
+	if d := walker.DirEntry(); d != nil {
+		t.Errorf("DirEntry() for missing root = %v, want nil", d)
+	}
+}
+
+func TestWalkModTime(t *testing.T) {
+	mtime := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := fs.MapFS{"r/a": {ModTime: mtime}}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		if walker.Path() == "r/a" && !walker.ModTime().Equal(mtime) {
+			t.Errorf("ModTime() = %v, want %v", walker.ModTime(), mtime)
+		}
+	}
+	walker = fs.WalkFS("missing", m)
+	walker.Step()
+	if mt := walker.ModTime(); !mt.IsZero() {
+		t.Errorf("ModTime() for missing root = %v, want zero", mt)
+	}
+}
+
+func TestWalkSize(t *testing.T) {
+	m := fs.MapFS{"r/a": {Data: []byte("hello")}}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		if walker.Path() == "r/a" && walker.Size() != 5 {
+			t.Errorf("Size() = %d, want 5", walker.Size())
+		}
+	}
+	walker = fs.WalkFS("missing", m)
+	walker.Step()
+	if n := walker.Size(); n != 0 {
+		t.Errorf("Size() for missing root = %d, want 0", n)
+	}
+}
+
+func TestWalkLinkTarget(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":   mapFile,
+		"r/b/c": mapFile,
+		"r/l":   {Data: []byte("b"), Mode: os.ModeSymlink | 0777},
+	}
+	walker := fs.WalkFS("r", m)
+	var got []string
+	for walker.Step() {
+		got = append(got, walker.Path())
+		dst, err := walker.LinkTarget()
+		if walker.Type() == fs.Symlink {
+			if err != nil || dst != "b" {
+				t.Errorf("LinkTarget() at %s = %q, %v, want %q", walker.Path(), dst, err, "b")
+			}
+		} else if err == nil {
+			t.Errorf("LinkTarget() at %s = %q, want error", walker.Path(), dst)
+		}
+	}
+	want := []string{"r", "r/a", "r/b", "r/b/c", "r/l"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkStatTarget(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":        {Data: []byte("hello")},
+		"r/d/e":      mapFile,
+		"r/dangling": {Data: []byte("missing"), Mode: os.ModeSymlink | 0777},
+		"r/l":        {Data: []byte("a"), Mode: os.ModeSymlink | 0777},
+		"r/ld":       {Data: []byte("d"), Mode: os.ModeSymlink | 0777},
+	}
+	want := map[string]string{
+		"r":          "d---------",
+		"r/a":        "----------",
+		"r/d":        "d---------",
+		"r/d/e":      "----------",
+		"r/dangling": "error",
+		"r/l":        "----------",
+		"r/ld":       "d---------",
+	}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+		info, err := walker.StatTarget()
+		got := "error"
+		if err == nil {
+			got = info.Mode().Type().String()
+		}
+		if got != want[walker.Path()] {
+			t.Errorf("StatTarget() at %s = %s, want %s", walker.Path(), got, want[walker.Path()])
+		}
+		if walker.Type() != fs.Symlink && info != walker.Stat() {
+			t.Errorf("StatTarget() at %s = %v, want Stat()", walker.Path(), info)
+		}
+	}
+	walker = fs.WalkFS("missing", m)
+	walker.Step()
+	if info, err := walker.StatTarget(); info != nil || err != walker.Err() {
+		t.Errorf("StatTarget() for missing root = %v, %v, want nil, %v", info, err, walker.Err())
+	}
+}
+
+func TestWalkEntry(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	entries := make(chan fs.Entry)
+	names := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range entries {
+				if filepath.Base(e.Path) != e.Info.Name() {
+					t.Errorf("entry %s has info for %s", e.Path, e.Info.Name())
+				}
+				names <- e.Info.Name()
+			}
+		}()
+	}
+	go func() {
+		walker := fs.Walk(tree.name)
+		for walker.Step() {
+			entries <- walker.Entry()
+		}
+		close(entries)
+		wg.Wait()
+		close(names)
+	}()
+	n := 0
+	for range names {
+		n++
+	}
+	if n != 10 {
+		t.Errorf("workers saw %d entries, want 10", n)
+	}
+}
+
+func TestWalkNext(t *testing.T) {
+	m := fs.MapFS{"r/a": mapFile, "r/b/c": mapFile}
+	var got []*fs.Entry
+	walker := fs.WalkFS("r", m)
+	for e, ok := walker.Next(); ok; e, ok = walker.Next() {
+		got = append(got, e)
+	}
+	want := []struct {
+		path  string
+		depth int
+	}{{"r", 0}, {"r/a", 1}, {"r/b", 1}, {"r/b/c", 2}}
+	if len(got) != len(want) {
+		t.Fatalf("Next returned %d entries, want %d", len(got), len(want))
+	}
+	for i, e := range got {
+		if e.Path != want[i].path || e.Depth != want[i].depth || e.Info == nil || e.Err != nil {
+			t.Errorf("entry %d = %+v, want %s at depth %d", i, e, want[i].path, want[i].depth)
+		}
+	}
+	if e, ok := walker.Next(); e != nil || ok {
+		t.Errorf("Next() at end = %v, %v, want nil, false", e, ok)
+	}
+}
+
+func TestWalkNextDir(t *testing.T) {
+	m := errFS{fs.MapFS{
+		"r/a":       mapFile,
+		"r/b/c":     mapFile,
+		"r/b/d/e":   mapFile,
+		"r/f/g":     mapFile,
+		"r/h/i":     mapFile,
+		"r/j/k/l/m": mapFile,
+	}, map[string]bool{"r/h": true}}
+	c := &countFS{FileSystem: m}
+	var got []string
+	walker := fs.WalkFS("r", c)
+	for dir, entries, ok := walker.NextDir(); ok; dir, entries, ok = walker.NextDir() {
+		s := dir + ":"
+		for _, info := range entries {
+			s += " " + info.Name()
+		}
+		if err := walker.Err(); err != nil {
+			s += " error"
+		}
+		got = append(got, s)
+		if dir == "r/f" {
+			walker.SkipDir()
+		}
+		if dir == "r/j" {
+			// Step visits the entries NextDir returned.
+			if !walker.Step() || walker.Path() != "r/j/k" {
+				t.Errorf("Step after NextDir visited %s, want r/j/k", walker.Path())
+			}
+		}
+	}
+	want := []string{
+		"r: a b f h j",
+		"r/b: c d",
+		"r/b/d: e",
+		"r/f: g",
+		"r/h: error",
+		"r/j: k",
+		"r/j/k/l: m",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NextDir returned %q, want %q", got, want)
+	}
+	if c.n != 8 {
+		t.Errorf("read %d directories, want 8", c.n)
+	}
+}
+
+func TestWalkErrorTypes(t *testing.T) {
+	m := errFS{fs.MapFS{"r/a": mapFile}, map[string]bool{"r": true}}
+	walker := fs.WalkFS("r", m)
+	walker.Step()
+	walker.Step()
+	var rerr *fs.ReadDirError
+	if err := walker.Err(); !errors.As(err, &rerr) || rerr.Path != "r" || !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Err() for unreadable directory = %#v, want *ReadDirError for r", err)
+	}
+
+	walker = fs.WalkFS("missing", m)
+	walker.Step()
+	var serr *fs.StatError
+	if err := walker.Err(); !errors.As(err, &serr) || serr.Path != "missing" || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Err() for missing root = %#v, want *StatError for missing", err)
+	}
+}
+
+func TestWalkChildCount(t *testing.T) {
+	m := errFS{fs.MapFS{
+		"r/a":     mapFile,
+		"r/b/c":   mapFile,
+		"r/b/d":   mapFile,
+		"r/e/f":   mapFile,
+		"r/g":     mapDir,
+		"r/h/i/j": mapFile,
+	}, map[string]bool{"r/h": true}}
+	c := &countFS{FileSystem: m}
+	walker := fs.WalkFS("r", c)
+	var got []string
+	for walker.Step() {
+		n := walker.ChildCount()
+		if walker.Path() == "r/e" {
+			walker.SkipDir()
+			n = walker.ChildCount()
+		}
+		if walker.ChildCount() != n {
+			t.Errorf("ChildCount() at %s changed from %d to %d", walker.Path(), n, walker.ChildCount())
+		}
+		s := fmt.Sprintf("%s %d", walker.Path(), n)
+		if walker.Err() != nil {
+			s += " error"
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"r 5",
+		"r/a -1",
+		"r/b 2",
+		"r/b/c -1",
+		"r/b/d -1",
+		"r/e -1",
+		"r/g 0",
+		"r/h -1",
+		"r/h -1 error",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+	if c.n != 5 {
+		t.Errorf("read %d directories, want 5", c.n)
+	}
+}
+
+func TestWalkSkipDirRoot(t *testing.T) {
+	root := t.TempDir()
+	r, q := filepath.Join(root, "r"), filepath.Join(root, "q")
+	for _, p := range []string{filepath.Join(r, "a"), filepath.Join(q, "b")} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		w    *fs.Walker
+		want []string
+	}{
+		{fs.Walk(r), []string{r}},
+		{fs.WalkBFS(r), []string{r}},
+		{fs.WalkPostOrder(r), []string{filepath.Join(r, "a"), r}},
+		{fs.WalkMulti(r, q), []string{r, q}},
+	}
+	for _, test := range tests {
+		var got []string
+		for test.w.Step() {
+			if test.w.Depth() == 0 {
+				test.w.SkipDir()
+			}
+			got = append(got, test.w.Path())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("walk = %q, want %q", got, test.want)
+		}
+		if err := test.w.WalkErr(); err != nil {
+			t.Errorf("WalkErr() = %v, want nil", err)
+		}
+	}
+}
+
+func TestWalkSkipSiblings(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":     mapFile,
+		"r/b/c":   mapFile,
+		"r/b/d":   mapFile,
+		"r/b/e/f": mapFile,
+		"r/g/h":   mapFile,
+		"r/i":     mapFile,
+	}
+	walker := fs.WalkFS("r", m)
+	var got []string
+	for walker.Step() {
+		switch walker.Path() {
+		case "r", "r/b/c", "r/g":
+			walker.SkipSiblings()
+		}
+		got = append(got, walker.Path())
+	}
+	want := []string{"r", "r/a", "r/b", "r/b/c", "r/g", "r/g/h"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkSkip(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":   mapFile,
+		"r/b/c": mapFile,
+		"r/d":   mapFile,
+		"r/e/f": mapFile,
+	}
+	walker := fs.WalkFS("r", m)
+	var got []string
+	for walker.Step() {
+		switch walker.Path() {
+		case "r/a", "r/b":
+			walker.Skip()
+		}
+		got = append(got, walker.Path())
+	}
+	want := []string{"r", "r/a", "r/b", "r/d", "r/e", "r/e/f"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkProgress(t *testing.T) {
+	m := fs.MapFS{"r/a": mapFile, "r/b/c": mapFile, "r/d": mapFile}
+	walker := fs.WalkFS("r", m)
+	var got []string
+	walker.SetProgress(2, func(count int, lastPath string) {
+		got = append(got, fmt.Sprintf("%d %s", count, lastPath))
+	})
+	for walker.Step() {
+		walker.Peek()
+	}
+	want := []string{"2 r/a", "4 r/b/c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("progress = %q, want %q", got, want)
+	}
+}
+
+func TestWalkStats(t *testing.T) {
+	m := errFS{
+		MapFS: fs.MapFS{
+			"r/a":    {Data: []byte("hello")},
+			"r/b/c":  {Data: []byte("x")},
+			"r/d/e":  mapFile,
+			"r/link": {Data: []byte("a"), Mode: os.ModeSymlink},
+		},
+		bad: map[string]bool{"r/d": true},
+	}
+	walker := fs.WalkFS("r", m)
+	for walker.Step() {
+	}
+	want := fs.Stats{Files: 2, Dirs: 3, Symlinks: 1, Bytes: 6, Errors: 1}
+	if got := walker.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWalkErrorHandler(t *testing.T) {
+	m := errFS{
+		MapFS: fs.MapFS{
+			"r/a/x": mapFile,
+			"r/b/y": mapFile,
+			"r/c/z": mapFile,
+			"r/d":   mapFile,
+		},
+		bad: map[string]bool{"r/a": true, "r/c": true},
+	}
+	errAbort := errors.New("abort")
+	tests := []struct {
+		ret  error
+		want []string
+		err  error
+	}{
+		{nil, []string{"r", "r/a", "r/b", "r/b/y", "r/c", "r/d"}, nil},
+		{filepath.SkipDir, []string{"r", "r/a", "r/b", "r/b/y", "r/c", "r/d"}, nil},
+		{fs.SkipDir, []string{"r", "r/a", "r/b", "r/b/y", "r/c", "r/d"}, nil},
+		{errAbort, []string{"r", "r/a"}, errAbort},
+	}
+	for _, test := range tests {
+		var got, handled []string
+		walker := fs.WalkFS("r", m)
+		walker.SetErrorHandler(func(path string, err error) error {
+			handled = append(handled, path)
+			return test.ret
+		})
+		for walker.Step() {
+			if walker.Err() != nil {
+				t.Fatalf("Err() = %v while stepping", walker.Err())
+			}
+			got = append(got, walker.Path())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("handler returning %v: walk = %q, want %q", test.ret, got, test.want)
+		}
+		if handled[0] != "r/a" {
+			t.Errorf("handler called for %q first, want %q", handled[0], "r/a")
+		}
+		if err := walker.Err(); err != test.err {
+			t.Errorf("handler returning %v: Err() = %v, want %v", test.ret, err, test.err)
+		}
+	}
+}
+
+func TestWalkErr(t *testing.T) {
+	m := fs.MapFS{"r/a": mapFile, "r/b": mapFile}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	boom := errors.New("boom")
+	tests := []struct {
+		name  string
+		w     *fs.Walker
+		setup func(w *fs.Walker)
+		want  error
+	}{
+		{"done", fs.WalkFS("r", m), nil, nil},
+		{"missing root", fs.WalkFS("missing", m), nil, nil},
+		{"stopped", fs.WalkFS("r", m), func(w *fs.Walker) { w.Stop() }, fs.ErrStopped},
+		{"canceled", fs.WalkContext(ctx, "r"), func(*fs.Walker) { cancel() }, context.Canceled},
+		{"handler", fs.WalkFS("r", errFS{m, map[string]bool{"r": true}}), func(w *fs.Walker) {
+			w.SetErrorHandler(func(string, error) error { return boom })
+		}, boom},
+	}
+	for _, test := range tests {
+		if test.setup != nil {
+			test.setup(test.w)
+		}
+		for test.w.Step() {
+		}
+		if err := test.w.WalkErr(); err != test.want {
+			t.Errorf("%s: WalkErr() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	m := errFS{
+		MapFS: fs.MapFS{"r/a": mapFile, "r/b/c": mapFile},
+		bad:   map[string]bool{"r/b": true},
+	}
+	entries, err := fs.Collect(fs.WalkFS("r", m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		if e.Err != nil {
+			got = append(got, "error "+e.Path)
+		} else {
+			got = append(got, e.Path)
+		}
+	}
+	want := []string{"r", "r/a", "r/b", "error r/b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect = %q, want %q", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fs.Collect(fs.WalkContext(ctx, "r")); err != context.Canceled {
+		t.Errorf("Collect error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWalkChan(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var want []string
+	for walker := fs.Walk(tree.name); walker.Step(); {
+		want = append(want, walker.Path())
+	}
+	var got []string
+	for e := range fs.WalkChan(context.Background(), tree.name) {
+		if e.Err != nil {
+			t.Errorf("%s: %v", e.Path, e.Err)
+		}
+		got = append(got, e.Path)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkChan = %q, want %q", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := fs.WalkChan(ctx, tree.name)
+	<-c
+	cancel()
+	n := 0
+	for range c {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("received %d entries after cancel, want at most 1", n)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	walker := fs.Walk(tree.name)
+	for walker.Step() {
+	}
+	if walker.Stopped() {
+		t.Errorf("Stopped() = true after a full walk")
+	}
+
+	for _, walker := range []*fs.Walker{fs.Walk(tree.name), fs.WalkParallel(tree.name, 4)} {
+		n := 0
+		for walker.Step() {
+			if n++; n == 3 {
+				walker.Stop()
+			}
+		}
+		if n != 3 {
+			t.Errorf("visited %d entries, want 3", n)
+		}
+		if !walker.Stopped() {
+			t.Errorf("Stopped() = false after Stop")
+		}
+		if err := walker.Err(); !errors.Is(err, fs.ErrStopped) {
+			t.Errorf("Err() = %v, want %v", err, fs.ErrStopped)
+		}
+	}
+}
+
+func TestWalkShallow(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var got []string
+	walker := fs.WalkShallow(tree.name)
+	for walker.Step() {
+		rel := filepath.ToSlash(walker.RelPath())
+		got = append(got, rel)
+		switch rel {
+		case ".", "d", "a":
+			walker.Descend()
+		case "b":
+			walker.Descend()
+			walker.SkipDir()
+		}
+	}
+	want := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+	walker = fs.WalkShallow(tree.name)
+	n := 0
+	for walker.Step() {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("visited %d entries without Descend, want 1", n)
+	}
+}
+
+func TestWalkUnordered(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	seen := make(map[string]bool)
+	var got []string
+	walker := fs.WalkUnordered(tree.name)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		rel := filepath.ToSlash(walker.RelPath())
+		if dir := path.Dir(rel); rel != "." && !seen[dir] {
+			t.Errorf("visited %s before its directory", rel)
+		}
+		seen[rel] = true
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkReadDirBatch(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var got []string
+	walker := fs.Walk(tree.name)
+	walker.SetReadDirBatch(2)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	sort.Strings(got)
+	want := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+
+	m := &batchFS{FileSystem: fs.MapFS{
+		"r/a":   mapFile,
+		"r/b/c": mapFile,
+		"r/b/d": mapFile,
+		"r/b/e": mapFile,
+		"r/f":   mapFile,
+		"r/g":   mapFile,
+	}}
+	tests := []struct {
+		name string
+		stop func(w *fs.Walker)
+		want []string
+	}{
+		{"whole", nil, []string{"r", "r/a", "r/b", "r/b/c", "r/b/d", "r/b/e", "r/f", "r/g"}},
+		{"SkipSiblings", (*fs.Walker).SkipSiblings, []string{"r", "r/a", "r/b", "r/b/c", "r/f", "r/g"}},
+		{"Stop", (*fs.Walker).Stop, []string{"r", "r/a", "r/b", "r/b/c"}},
+	}
+	for _, test := range tests {
+		var got []string
+		walker := fs.WalkFS("r", m)
+		walker.SetReadDirBatch(2)
+		for walker.Step() {
+			if walker.Path() == "r/b/c" && test.stop != nil {
+				test.stop(walker)
+			}
+			got = append(got, walker.Path())
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: visited %q, want %q", test.name, got, test.want)
+		}
+		if m.open != 0 {
+			t.Errorf("%s: %d directories left open", test.name, m.open)
+		}
+		if m.max != 2 {
+			t.Errorf("%s: read at most %d entries at once, want 2", test.name, m.max)
+		}
+	}
+
+	// Walks that read whole directories keep their order.
+	orders := []struct {
+		opt  fs.Option
+		want []string
+	}{
+		{fs.WithReadDirBatch(0), []string{"r", "r/a", "r/b", "r/b/c", "r/b/d", "r/b/e", "r/f", "r/g"}},
+		{fs.WithBFS(), []string{"r", "r/a", "r/b", "r/f", "r/g", "r/b/c", "r/b/d", "r/b/e"}},
+		{fs.WithPostOrder(), []string{"r/a", "r/b/c", "r/b/d", "r/b/e", "r/b", "r/f", "r/g", "r"}},
+	}
+	for _, test := range orders {
+		var got []string
+		walker := fs.Walk("r", fs.WithFileSystem(m), fs.WithReadDirBatch(2), test.opt)
+		for walker.Step() {
+			got = append(got, walker.Path())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("walk = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestWalkFrom(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	all := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}
+	tests := []struct {
+		after string
+		want  []string
+	}{
+		{".", all[1:]},
+		{"a", all[2:]},
+		{"b", all[3:]},
+		{"bb", all[3:]},
+		{"d", all[5:]},
+		{"d/y", all[7:]},
+		{"d/z/v", []string{}},
+		{"..", all},
+	}
+	for _, test := range tests {
+		after := filepath.Join(tree.name, filepath.FromSlash(test.after))
+		walker := fs.WalkFrom(tree.name, after)
+		got := []string{}
+		for walker.Step() {
+			got = append(got, filepath.ToSlash(walker.RelPath()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("WalkFrom(%q) = %q, want %q", test.after, got, test.want)
+		}
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var got []string
+	walker := fs.WalkFiles(tree.name)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	want := []string{"a", "c", "d/x", "d/z/u", "d/z/v"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkDirs(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var got []string
+	walker := fs.WalkDirs(tree.name)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	want := []string{".", "b", "d", "d/y", "d/z"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkSince(t *testing.T) {
+	root := t.TempDir()
+	since := time.Now().Add(-time.Hour)
+	for _, name := range []string{"a", "d/b", "d/c", "e/f"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := since.Add(-time.Hour)
+	for _, name := range []string{"a", "d/b", "e/f", "d", "."} {
+		if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(name)), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	walker := fs.WalkSince(root, since)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	want := []string{"d/c", "e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkN(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	for _, n := range []int{0, 3, 6, 100} {
+		got := []string{}
+		walker := fs.WalkN(tree.name, n)
+		for walker.Step() {
+			rel := filepath.ToSlash(walker.RelPath())
+			got = append(got, rel)
+			if rel == "b" || rel == "d" {
+				walker.SkipDir()
+			}
+		}
+		want := []string{".", "a", "b", "c", "d"}
+		if n < len(want) {
+			want = want[:n]
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("WalkN(%d) = %q, want %q", n, got, want)
+		}
+		if walker.Stopped() != (n < 5) {
+			t.Errorf("WalkN(%d): Stopped() = %v", n, walker.Stopped())
+		}
+	}
+}
+
+func TestWalkWithInfo(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	info, err := os.Lstat(tree.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range []os.FileInfo{info, nil} {
+		walker := fs.WalkWithInfo(tree.name, fi)
+		n := 0
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if n == 0 {
+				if !walker.IsDir() || fi != nil && walker.Stat() != fi {
+					t.Errorf("WalkWithInfo(%v): root info = %v", fi, walker.Stat())
+				}
+			}
+			n++
+		}
+		if n != 10 {
+			t.Errorf("WalkWithInfo(%v) visited %d entries, want 10", fi, n)
+		}
+	}
+}
+
+func TestWalkAbs(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker := fs.WalkAbs("./" + tree.name + "/")
+	n := 0
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		want := filepath.Join(wd, tree.name, walker.RelPath())
+		if walker.Path() != want {
+			t.Errorf("Path() = %q, want %q", walker.Path(), want)
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("visited %d entries, want 10", n)
+	}
+	if root := filepath.Join(wd, tree.name); walker.Root() != root {
+		t.Errorf("Root() = %q, want %q", walker.Root(), root)
+	}
+}
+
+func TestWalkPause(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	walker := fs.Walk(tree.name)
+	var got []string
+	for i := 0; walker.Step(); i++ {
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+		if i%3 != 0 {
+			continue
+		}
+		path := walker.Path()
+		walker.Pause()
+		walker.Pause()
+		for j := 0; j < 2; j++ {
+			if walker.Step() {
+				t.Fatalf("Step() = true while paused")
+			}
+			if err := walker.Err(); err != fs.ErrPaused {
+				t.Fatalf("Err() = %v while paused, want %v", err, fs.ErrPaused)
+			}
+			if _, _, err, ok := walker.Peek(); ok || err != fs.ErrPaused {
+				t.Fatalf("Peek() = %v, %v while paused, want %v, false", err, ok, fs.ErrPaused)
+			}
+		}
+		walker.Resume()
+		walker.Resume()
+		if walker.Path() != path {
+			t.Errorf("Path() = %q after Resume, want %q", walker.Path(), path)
+		}
+	}
+	want := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+	if err := walker.Err(); err != nil {
+		t.Errorf("Err() = %v at end of walk", err)
+	}
+}
+
+func TestWalkStopEarly(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	before := runtime.NumGoroutine()
+	for _, walker := range []*fs.Walker{fs.Walk(tree.name), fs.WalkParallel(tree.name, 4)} {
+		walker.Stop()
+		if walker.Step() {
+			t.Errorf("Step() = true after Stop")
+		}
+	}
+	walker := fs.WalkParallel(tree.name, 4)
+	walker.Step()
+	walker.Step()
+	walker.Stop()
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWalkClose(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var c io.Closer = fs.WalkParallel(tree.name, 4)
+	walker := c.(*fs.Walker)
+	walker.Step()
+	walker.Step()
+	if err := walker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := walker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if walker.Step() {
+		t.Errorf("Step() = true after Close")
+	}
+}
+
+func TestWalkContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	walker := fs.WalkContext(ctx, runtime.GOROOT())
+	n := 0
+	for walker.Step() {
+		if n++; n == 3 {
+			cancel()
+		}
+	}
+	if n != 3 {
+		t.Errorf("visited %d entries, want 3", n)
+	}
+	if err := walker.Err(); err != context.Canceled {
+		t.Errorf("Err() = %v, want %v", err, context.Canceled)
+	}
+	if walker.Step() {
+		t.Errorf("Step() = true after cancel")
+	}
+}
+
+func TestWalkTimeout(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	walker := fs.WalkTimeout(tree.name, time.Hour)
+	n := 0
+	for walker.Step() {
+		n++
+	}
+	if n != 10 || walker.WalkErr() != nil {
+		t.Errorf("visited %d entries, WalkErr() = %v, want 10, nil", n, walker.WalkErr())
+	}
+
+	walker = fs.WalkTimeout(tree.name, 10*time.Millisecond)
+	if !walker.Step() {
+		t.Fatalf("Step() = false before the timeout")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if walker.Step() {
+		t.Errorf("Step() = true after the timeout")
+	}
+	if err := walker.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Err() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Reset starts the time over.
+	walker.Reset(tree.name)
+	if !walker.Step() {
+		t.Errorf("Step() = false after Reset, Err() = %v", walker.Err())
+	}
+}
+
+func TestWalkName(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	walker := fs.Walk(tree.name + string(filepath.Separator))
+	for walker.Step() {
+		if got, want := walker.Name(), filepath.Base(walker.Path()); got != want {
+			t.Errorf("Name() = %q, want %q", got, want)
+		}
+		if walker.Depth() == 0 && walker.Name() != tree.name {
+			t.Errorf("root Name() = %q, want %q", walker.Name(), tree.name)
+		}
+	}
+}
+
+func TestWalkSingleFile(t *testing.T) {
+	m := fs.MapFS{
+		"a":    mapFile,
+		"b/c":  mapFile,
+		"link": {Data: []byte("b"), Mode: os.ModeSymlink | 0777},
+	}
+	tests := []struct {
+		w    *fs.Walker
+		want []string
+	}{
+		{fs.WalkFS("a", m), []string{"a true"}},
+		{fs.WalkFS("link", m), []string{"link true"}},
+		{fs.WalkFS("b", m), []string{"b false", "b/c false"}},
+		{fs.WalkFS("missing", m), []string{"missing false"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for test.w.Step() {
+			if test.w.IsSingleFile() {
+				test.w.SkipDir()
+				if d := test.w.Depth(); d != 0 {
+					t.Errorf("Depth() = %d on %s, want 0", d, test.w.Path())
+				}
+			}
+			got = append(got, fmt.Sprintf("%s %t", test.w.Path(), test.w.IsSingleFile()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("walk = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestWalkFollow(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"real", "real/sub"} {
+		if err := os.Mkdir(filepath.Join(root, d), 0770); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"link":          "real",
+		"real/sub/loop": "..",
+		"dangling":      "missing",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	var got []string
+	walker := fs.WalkFollow(root)
+	for walker.Step() {
+		rel, _ := filepath.Rel(root, walker.Path())
+		rel = filepath.ToSlash(rel)
+		if err := walker.Err(); err != nil {
+			if !errors.Is(err, fs.ErrCycle) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			rel = "cycle " + rel
+		}
+		got = append(got, rel)
+	}
+	want := []string{
+		".",
+		"dangling",
+		"link",
+		"link/sub",
+		"cycle link/sub/loop",
+		"real",
+		"real/sub",
+		"cycle real/sub/loop",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+// loopFS is the OS FileSystem with an extra entry, in directory at,
+// that is the directory to.
+type loopFS struct {
+	fs.FileSystem
+	at, to string
+}
+
+func (l loopFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	list, err := l.FileSystem.ReadDir(dirname)
+	if dirname == l.at {
+		info, err := os.Lstat(l.to)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	return list, err
+}
+
+func TestWalkFollowDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"plugins", "real", "other"} {
+		if err := os.Mkdir(filepath.Join(root, d), 0770); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "real", "x"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"plugins/p":    "../real",
+		"plugins/self": ".",
+		"real/nested":  "../other",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	var got []string
+	walker := fs.WalkFollowDepth(filepath.Join(root, "plugins"), 1)
+	for walker.Step() {
+		rel := filepath.ToSlash(walker.RelPath())
+		if err := walker.Err(); err != nil {
+			if !errors.Is(err, fs.ErrCycle) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			rel = "cycle " + rel
+		} else if walker.IsDir() {
+			rel += "/"
+		}
+		got = append(got, rel)
+	}
+	want := []string{
+		"./",
+		"p/",
+		"p/nested",
+		"p/x",
+		"cycle self",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkDetectCycles(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	fsys := loopFS{fs.OS(), filepath.Join(tree.name, "d", "z"), filepath.Join(tree.name, "d")}
+	var got []string
+	walker := fs.WalkFS(tree.name, fsys)
+	walker.DetectCycles(true)
+	for walker.Step() {
+		rel := filepath.ToSlash(walker.RelPath())
+		if err := walker.Err(); err != nil {
+			if !errors.Is(err, fs.ErrCycle) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			rel = "cycle " + rel
+		}
+		got = append(got, rel)
+	}
+	want := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v", "cycle d/z/d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkMinDepth(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":       mapFile,
+		"r/b/c":     mapFile,
+		"r/b/d/e":   mapFile,
+		"r/f/g/h/i": mapFile,
+	}
+	var got []string
+	walker := fs.WalkFS("r", m)
+	walker.SetMinDepth(2)
+	walker.SetMaxDepth(3)
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	want := []string{"r/b/c", "r/b/d", "r/b/d/e", "r/f/g", "r/f/g/h"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkSortFunc(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":   mapFile,
+		"r/b/c": mapFile,
+		"r/d":   mapFile,
+		"r/e/f": mapFile,
+		"r/e/g": mapFile,
+	}
+	var got []string
+	walker := fs.WalkFS("r", m)
+	walker.SetSortFunc(func(a, b os.FileInfo) bool {
+		return a.IsDir() && !b.IsDir()
+	})
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	want := []string{"r", "r/b", "r/b/c", "r/e", "r/e/f", "r/e/g", "r/a", "r/d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkOrdered(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	tests := []struct {
+		dirsFirst bool
+		want      []string
+	}{
+		{true, []string{".", "b", "d", "d/y", "d/z", "d/z/u", "d/z/v", "d/x", "a", "c"}},
+		{false, []string{".", "a", "c", "b", "d", "d/x", "d/y", "d/z", "d/z/u", "d/z/v"}},
+	}
+	for _, test := range tests {
+		var got []string
+		walker := fs.WalkOrdered(tree.name, test.dirsFirst)
+		for walker.Step() {
+			got = append(got, filepath.ToSlash(walker.RelPath()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("dirsFirst %v: walk = %q, want %q", test.dirsFirst, got, test.want)
+		}
+	}
+}
+
+func TestWalkDirFilter(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":     mapFile,
+		"r/b/c":   mapFile,
+		"r/d/e/f": mapFile,
+		"r/d/g":   mapFile,
+	}
+	c := &countFS{FileSystem: m}
+	var got, asked []string
+	walker := fs.WalkFS("r", c)
+	walker.SetDirFilter(func(path string, info os.FileInfo) bool {
+		asked = append(asked, path)
+		return path != "r/d"
+	})
+	for walker.Step() {
+		got = append(got, walker.Path())
+		if walker.Path() == "r/b" {
+			walker.SkipDir()
+		}
+	}
+	want := []string{"r", "r/a", "r/b", "r/d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+	if want := []string{"r", "r/d"}; !reflect.DeepEqual(asked, want) {
+		t.Errorf("dir filter called for %q, want %q", asked, want)
+	}
+	if c.n != 1 {
+		t.Errorf("read %d directories, want 1", c.n)
+	}
+}
+
+func TestWalkIgnoreMissingRoot(t *testing.T) {
+	m := errFS{fs.MapFS{"r/a": mapFile, "s/b": mapFile}, map[string]bool{"s": true}}
+	tests := []struct {
+		root   string
+		enable bool
+		want   []string
+	}{
+		{"missing", false, []string{"missing error"}},
+		{"missing", true, nil},
+		{"r", true, []string{"r", "r/a"}},
+		{"s", true, []string{"s", "s error"}},
+	}
+	for _, test := range tests {
+		walker := fs.WalkFS(test.root, m)
+		walker.IgnoreMissingRoot(test.enable)
+		var got []string
+		for walker.Step() {
+			s := walker.Path()
+			if walker.Err() != nil {
+				s += " error"
+			}
+			got = append(got, s)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("IgnoreMissingRoot(%v) on %s: visited %q, want %q", test.enable, test.root, got, test.want)
+		}
+	}
+}
+
+func TestWalkSkipHidden(t *testing.T) {
+	m := fs.MapFS{
+		".r/a":     mapFile,
+		".r/.b":    mapFile,
+		".r/.c/d":  mapFile,
+		".r/e/.f":  mapFile,
+		".r/e/g":   mapFile,
+		".r/e/h.i": mapDir,
+	}
+	var got []string
+	walker := fs.WalkFS(".r", m)
+	walker.SkipHidden(true)
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	want := []string{".r", ".r/a", ".r/e", ".r/e/g", ".r/e/h.i"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SkipHidden(true) visited %q, want %q", got, want)
+	}
+	walker = fs.WalkFS(".r", m)
+	walker.SkipHidden(false)
+	n := 0
+	for walker.Step() {
+		n++
+	}
+	if n != 9 {
+		t.Errorf("SkipHidden(false) visited %d entries, want 9", n)
+	}
+}
+
+func TestWalkFilter(t *testing.T) {
+	m := fs.MapFS{
+		"r":        mapDir,
+		"r/a.go":   mapFile,
+		"r/b.txt":  mapFile,
+		"r/.git":   mapDir,
+		"r/.git/c": mapFile,
+		"r/d":      mapDir,
+		"r/d/e.go": mapFile,
+	}
+	var got []string
+	walker := fs.WalkFS("r", m)
+	walker.SetFilter(func(p string, info os.FileInfo) bool {
+		if info.IsDir() {
+			return !strings.HasPrefix(info.Name(), ".")
+		}
+		return path.Ext(p) == ".go"
+	})
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	want := []string{"r", "r/a.go", "r/d", "r/d/e.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	walker = fs.WalkFS("r", m)
+	walker.SetFilter(func(string, os.FileInfo) bool { return false })
+	if walker.Step() {
+		t.Errorf("Step() = true with root filtered out")
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	walker := fs.WalkErrors("testdata-missing")
+	for walker.Step() {
+	}
+	var statErr *fs.StatError
+	if errs := walker.Errors(); len(errs) != 1 || !errors.As(errs[0], &statErr) || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Errorf("Errors() = %v, want one not-exist *StatError", errs)
+	}
+
+	if os.Getuid() == 0 {
+		t.Skip("permission errors cannot be produced as root")
+	}
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	b := filepath.Join(tree.name, tree.entries[1].name)
+	d := filepath.Join(tree.name, tree.entries[3].name)
+	os.Chmod(b, 0)
+	os.Chmod(d, 0)
+	defer os.Chmod(b, 0770)
+	defer os.Chmod(d, 0770)
+
+	walker = fs.WalkErrors(tree.name)
+	for walker.Step() {
+	}
+	var got []string
+	for _, err := range walker.Errors() {
+		var readErr *fs.ReadDirError
+		if !errors.As(err, &readErr) {
+			t.Fatalf("Errors() has %v, want a *ReadDirError", err)
+		}
+		got = append(got, readErr.Path)
+	}
+	if want := []string{b, d}; !reflect.DeepEqual(got, want) {
+		t.Errorf("error paths = %q, want %q", got, want)
+	}
+}
+
+func TestWalkReset(t *testing.T) {
+	m := fs.MapFS{
+		"r":   mapDir,
+		"r/a": mapFile,
+		"r/b": mapDir,
+		"s":   mapDir,
+		"s/c": mapFile,
+	}
+	walker := fs.WalkFS("r", m)
+	if root := walker.Root(); root != "r" {
+		t.Errorf("Root() = %q, want %q", root, "r")
+	}
+	walker.Step()
+	walker.Step()
+	if n := walker.Count(); n != 2 {
+		t.Errorf("Count() = %d, want 2", n)
+	}
+	walker.Reset("s")
+	if root := walker.Root(); root != "s" {
+		t.Errorf("Root() after Reset = %q, want %q", root, "s")
+	}
+	var got []string
+	for walker.Step() {
+		got = append(got, walker.Path())
+	}
+	if want := []string{"s", "s/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walk after Reset = %q, want %q", got, want)
+	}
+	if n := walker.Count(); n != 2 {
+		t.Errorf("Count() after Reset = %d, want 2", n)
+	}
+}
+
+func TestWalkExpand(t *testing.T) {
+	m := fs.MapFS{
+		"r/a":     mapFile,
+		"r/b/c":   mapFile,
+		"r/b/d/e": mapFile,
+		"r/b/x":   mapFile,
+		"r/f":     mapFile,
+	}
+	walker := fs.WalkFS("r", m)
+	walker.SetFilter(func(path string, info os.FileInfo) bool {
+		return info.Name() != "x"
+	})
+	walker.SetMaxDepth(2)
+	var got, expanded []string
+	for walker.Step() {
+		if walker.Path() == "r/b" {
+			walker.SkipDir()
+			sub := walker.Expand("r/b")
+			for sub.Step() {
+				expanded = append(expanded, fmt.Sprintf("%s %d", sub.Path(), sub.Depth()))
+			}
+		}
+		got = append(got, walker.Path())
+	}
+	if want := []string{"r", "r/a", "r/b", "r/f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+	if want := []string{"r/b 1", "r/b/c 2", "r/b/d 2"}; !reflect.DeepEqual(expanded, want) {
+		t.Errorf("expanded walk = %q, want %q", expanded, want)
+	}
+}
+
+func TestWalkMulti(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	b := filepath.Join(tree.name, "b")
+	d := filepath.Join(tree.name, "d")
+	z := filepath.Join(d, "z")
+	var got []string
+	walker := fs.WalkMulti(d, b, "testdata-missing")
+	for walker.Step() {
+		got = append(got, walker.Path())
+		if walker.Path() == z {
+			walker.SkipDir()
+		}
+	}
+	want := []string{
+		d,
+		filepath.Join(d, "x"),
+		filepath.Join(d, "y"),
+		z,
+		b,
+		"testdata-missing",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkPostOrder(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	var got []string
+	walker := fs.WalkPostOrder(tree.name)
+	walker.SetFilter(func(path string, info os.FileInfo) bool {
+		return info.Name() != "z"
+	})
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(walker.RelPath()))
+	}
+	want := []string{"a", "b", "c", "d/x", "d/y", "d", "."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+}
+
+func TestWalkBFS(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	for _, skip := range []string{"", "d/z"} {
+		var got []string
+		walker := fs.WalkBFS(tree.name)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				t.Fatal(err)
+			}
+			rel := filepath.ToSlash(walker.RelPath())
+			got = append(got, rel)
+			if rel == skip {
+				walker.SkipDir()
+			}
+		}
+		want := []string{".", "a", "b", "c", "d", "d/x", "d/y", "d/z"}
+		if skip == "" {
+			want = append(want, "d/z/u", "d/z/v")
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("skipping %q: walk = %q, want %q", skip, got, want)
+		}
+	}
+}
+
+func TestWalkParallel(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	skip := filepath.Join(tree.name, "d", "z")
+	var want []string
+	walker := fs.Walk(tree.name)
+	for walker.Step() {
+		want = append(want, walker.Path())
+		if walker.Path() == skip {
+			walker.SkipDir()
+		}
+	}
+	for _, workers := range []int{1, 2, 8} {
+		var got []string
+		walker := fs.WalkParallel(tree.name, workers)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, walker.Path())
+			if walker.Path() == skip {
+				walker.SkipDir()
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: walk = %q, want %q", workers, got, want)
+		}
+	}
+}
+
+// countFS counts the directories read through it.
+type countFS struct {
+	fs.FileSystem
+	n int
+}
+
+func (c *countFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	c.n++
+	return c.FileSystem.ReadDir(dirname)
+}
+
+// batchFS reads directories in batches, keeping track
+// of how many it has open.
+type batchFS struct {
+	fs.FileSystem
+	open, max int
+}
+
+func (b *batchFS) ReadDirBatch(dirname string, n int) (fs.DirIterator, error) {
+	list, err := b.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	b.open++
+	return &batchIterator{b, list, n}, nil
+}
+
+type batchIterator struct {
+	fs   *batchFS
+	list []os.FileInfo
+	n    int
+}
+
+func (it *batchIterator) Next() ([]os.FileInfo, error) {
+	if len(it.list) == 0 {
+		return nil, io.EOF
+	}
+	n := it.n
+	if n > len(it.list) {
+		n = len(it.list)
+	}
+	if n > it.fs.max {
+		it.fs.max = n
+	}
+	list := it.list[:n]
+	it.list = it.list[n:]
+	return list, nil
+}
+
+func (it *batchIterator) Close() error {
+	it.fs.open--
+	return nil
+}
+
+func TestWalkOS(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	c := &countFS{FileSystem: fs.OS()}
+	walker := fs.WalkFS(tree.name, c)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.n != 5 {
+		t.Errorf("read %d directories, want 5", c.n)
+	}
+}
+
+func TestOSModify(t *testing.T) {
+	root := t.TempDir()
+	fsys := fs.OS()
+	dir := fsys.Join(root, "a", "b")
+	for i := 0; i < 2; i++ {
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := fsys.Join(dir, "c")
+	if err := fsys.WriteFile(name, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(name); err != nil || string(b) != "hello" {
+		t.Errorf("read %q, %v, want %q", b, err, "hello")
+	}
+	if err := fsys.MkdirAll(name, 0755); err == nil {
+		t.Errorf("MkdirAll succeeded on a file")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abs, err := fsys.Abs("x/../y"); err != nil || abs != filepath.Join(wd, "y") {
+		t.Errorf("Abs = %q, %v, want %q", abs, err, filepath.Join(wd, "y"))
+	}
+	pattern := fsys.Join(root, "*", "*", "c")
+	if got, err := fsys.Glob(pattern); err != nil || !reflect.DeepEqual(got, []string{name}) {
+		t.Errorf("Glob(%q) = %q, %v, want %q", pattern, got, err, []string{name})
+	}
+	if err := fsys.Remove(dir); err == nil {
+		t.Errorf("Remove succeeded on a non-empty directory")
+	}
+	if err := fsys.Remove(name); err != nil {
+		t.Error(err)
+	}
+	if err := fsys.RemoveAll(fsys.Join(root, "a")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Lstat(fsys.Join(root, "a")); !os.IsNotExist(err) {
+		t.Errorf("Lstat after RemoveAll: %v, want not exist", err)
+	}
+
+	// Remove a tree one entry at a time, each directory after its entries.
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	for walker := fs.WalkPostOrder(tree.name); walker.Step(); {
+		if err := fsys.Remove(walker.Path()); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Lstat(tree.name); !os.IsNotExist(err) {
+		t.Errorf("Lstat after removing the tree: %v, want not exist", err)
+	}
+}
+
+// cacheFS serves ReadDir from memory, so that benchmarks measure
+// the Walker rather than the FileSystem.
+type cacheFS struct {
+	fs.FileSystem
+	dirs map[string][]os.FileInfo
+}
+
+func newCacheFS(fsys fs.FileSystem, root string) *cacheFS {
+	c := &cacheFS{fsys, make(map[string][]os.FileInfo)}
+	for walker := fs.WalkFS(root, fsys); walker.Step(); {
+		if walker.IsDir() {
+			c.dirs[walker.Path()], _ = fsys.ReadDir(walker.Path())
+		}
+	}
+	return c
+}
+
+func (c *cacheFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return c.dirs[dirname], nil
+}
+
+func BenchmarkWalk(b *testing.B) {
+	m := make(fs.MapFS)
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 100; j++ {
+			m[path.Join("r", strconv.Itoa(i), strconv.Itoa(j))] = mapFile
+		}
+	}
+	c := newCacheFS(m, "r")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for walker := fs.WalkFS("r", c); walker.Step(); {
+		}
+	}
+}
+
+func BenchmarkWalkSmall(b *testing.B) {
+	m := fs.MapFS{"r/a": mapFile, "r/b/c": mapFile}
+	c := newCacheFS(m, "r")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for walker := fs.WalkFS("r", c); walker.Step(); {
+		}
+	}
+}
+
+func BenchmarkWalkLargeDir(b *testing.B) {
+	root := b.TempDir()
+	for i := 0; i < 20000; i++ {
+		f, err := os.Create(filepath.Join(root, strconv.Itoa(i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+	}
+	for _, bench := range []struct {
+		name string
+		walk func(root string) *fs.Walker
+	}{
+		{"sorted", func(root string) *fs.Walker { return fs.Walk(root) }},
+		{"unsorted", fs.WalkUnordered},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for walker := bench.walk(root); walker.Step(); {
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWalkSkipDir(b *testing.B) {
+	root := b.TempDir()
+	for i := 0; i < 100; i++ {
+		dir := filepath.Join(root, strconv.Itoa(i))
+		os.Mkdir(dir, 0755)
+		for j := 0; j < 100; j++ {
+			f, err := os.Create(filepath.Join(dir, strconv.Itoa(j)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	}
+	for _, skip := range []bool{false, true} {
+		b.Run(fmt.Sprintf("skip=%v", skip), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for walker := fs.Walk(root); walker.Step(); {
+					if skip && walker.Depth() == 1 {
+						walker.SkipDir()
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package fs
+
+import (
+	"path/filepath"
+)
+
+// WalkCallback walks the tree rooted at root, calling fn for each
+// file or directory in the tree, including root, in the manner of
+// filepath.Walk. If fn returns SkipDir on a directory, the
+// directory is skipped; on any other file, the remaining files in
+// its directory are skipped. Any other non-nil error from fn stops
+// the walk, and WalkCallback returns it. As for filepath.Walk, fn
+// is given errors as the FileSystem returned them, not wrapped in
+// a *ReadDirError or a *StatError.
+func WalkCallback(root string, fn filepath.WalkFunc) error {
+	w := Walk(root)
+	for w.Step() {
+		err := fn(w.Path(), w.Stat(), entryErr(w.Err()))
+		if err == SkipDir {
+			if w.IsDir() {
+				w.SkipDir()
+			} else {
+				w.skipSiblings(w.cur)
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestWalkCallback(t *testing.T) {
+	makeTree(t)
+	defer os.RemoveAll(tree.name)
+	errStop := errors.New("stop")
+	for _, stop := range []string{"", "c"} {
+		walk := func(walk func(string, filepath.WalkFunc) error) ([]string, error) {
+			var got []string
+			err := walk(tree.name, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				got = append(got, path)
+				switch info.Name() {
+				case "b", "x":
+					return filepath.SkipDir
+				case stop:
+					return errStop
+				}
+				return nil
+			})
+			return got, err
+		}
+		want, wantErr := walk(filepath.Walk)
+		got, err := walk(fs.WalkCallback)
+		if err != wantErr {
+			t.Errorf("WalkCallback error = %v, want %v", err, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("WalkCallback visited %q, want %q", got, want)
+		}
+	}
+}
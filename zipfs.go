@@ -0,0 +1,45 @@
+package fs
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+)
+
+// zipFS is a FileSystem backed by a zip archive.
+type zipFS struct {
+	ioFS
+}
+
+// ZipFS returns a FileSystem that presents the files in the zip
+// archive r as a tree, with the directories implied by the names
+// in the archive synthesized as needed, and Open returning the
+// decompressed content of a file. Paths follow the same rules as
+// for FromIOFS: they are slash-separated, and the root is ".".
+//
+// Symbolic links stored in the archive are reported as such, and
+// Readlink returns their destination, but they are never followed:
+// Stat is the same as Lstat.
+func ZipFS(r *zip.Reader) FileSystem {
+	return zipFS{ioFS{r}}
+}
+
+func (z zipFS) Readlink(name string) (string, error) {
+	info, err := z.Lstat(name)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	f, err := z.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	dst, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(dst), nil
+}
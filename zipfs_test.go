@@ -0,0 +1,77 @@
+package fs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/kr/fs"
+)
+
+func TestZipFS(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name, data string
+		mode       os.FileMode
+	}{
+		{"a", "hello", 0644},
+		{"b/c", "x", 0644},
+		{"b/d/", "", os.ModeDir | 0755},
+		{"b/link", "../a", os.ModeSymlink | 0777},
+	} {
+		hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+		hdr.SetMode(f.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fs.ZipFS(zr)
+
+	var got []string
+	walker := fs.WalkFS(".", fsys)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, walker.Path()+" "+walker.Stat().Mode().Type().String())
+	}
+	want := []string{
+		". d---------",
+		"a ----------",
+		"b d---------",
+		"b/c ----------",
+		"b/d d---------",
+		"b/link L---------",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %q, want %q", got, want)
+	}
+
+	r, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if b, _ := ioutil.ReadAll(r); string(b) != "hello" {
+		t.Errorf("read %q, want %q", b, "hello")
+	}
+	if dst, err := fsys.Readlink("b/link"); err != nil || dst != "../a" {
+		t.Errorf("Readlink = %q, %v, want %q", dst, err, "../a")
+	}
+	if _, err := fsys.Readlink("a"); err == nil {
+		t.Errorf("Readlink succeeded on a regular file")
+	}
+}